@@ -20,7 +20,10 @@ func main() {
 		zap.S().Fatalf("Failed to create App:\n%f", err)
 	}
 
-	grpcServer := grpc.NewServer(application)
+	grpcServer, err := grpc.NewServer(application)
+	if err != nil {
+		zap.S().Fatalf("Failed to create gRPC server:\n%f", err)
+	}
 	go func() {
 		err = grpcServer.Run("0.0.0.0:50051")
 		if err != nil {
@@ -28,7 +31,10 @@ func main() {
 		}
 	}()
 
-	httpServer := http.NewServer(application)
+	httpServer, err := http.NewServer(application)
+	if err != nil {
+		zap.S().Fatalf("Failed to create HTTP server:\n%f", err)
+	}
 	err = httpServer.Run("0.0.0.0:8000")
 	if err != nil {
 		zap.S().Fatalf("Failed to run HTTP server:\n%f", err)
@@ -3,6 +3,7 @@ package app
 import (
 	"github.com/DangeL187/erax"
 
+	deviceDomain "auth/internal/features/device/domain"
 	deviceInfra "auth/internal/features/device/infra"
 	deviceModule "auth/internal/features/device/module"
 	userInfra "auth/internal/features/user/infra"
@@ -36,7 +37,7 @@ func NewApp() (*App, error) {
 		return nil, erax.Wrap(err, "failed to connect to DB")
 	}
 
-	app.JWTManager, err = jwt.NewJWTManager()
+	app.JWTManager, err = jwt.NewJWTManager(app.Config)
 	if err != nil {
 		return nil, erax.Wrap(err, "failed to create JWT Manager")
 	}
@@ -47,7 +48,10 @@ func NewApp() (*App, error) {
 	}
 
 	deviceRepo := deviceInfra.NewDeviceRepo(db)
-	app.DeviceModule = deviceModule.NewModule(deviceRepo, app.JWTManager)
+	app.DeviceModule = deviceModule.NewModule(deviceRepo, app.JWTManager, deviceDomain.BrokerCredentials{
+		Username: app.Config.MQTTServiceUsername,
+		Password: app.Config.MQTTServicePassword,
+	})
 
 	userRepo := userInfra.NewUserRepo(db)
 	app.UserModule = userModule.NewModule(userRepo, app.JWTManager, app.RoleManager)
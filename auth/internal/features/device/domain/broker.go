@@ -0,0 +1,24 @@
+package domain
+
+const (
+	BrokerActionPublish   = "publish"
+	BrokerActionSubscribe = "subscribe"
+)
+
+// BrokerCredentials is the MQTT login of a backend service such as ingress; an empty password disables it.
+type BrokerCredentials struct {
+	Username string
+	Password string
+}
+
+type BrokerClient struct {
+	ClientID string
+	Username string
+	Password string
+}
+
+type BrokerAccess struct {
+	Username string
+	Topic    string
+	Action   string
+}
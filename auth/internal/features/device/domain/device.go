@@ -4,9 +4,21 @@ type Device struct {
 	ID           uint
 	DeviceID     string
 	PasswordHash string
+
+	// per-device overrides of the ingress rate limit, nil to use the ingress defaults
+	RateLimit *float64 // messages per second, 0 for unlimited
+	RateBurst *int
+
+	// disabled devices cannot log in, refresh tokens or connect to the broker, and ingress rejects their messages
+	Disabled bool
 }
 
 type DeviceInputData struct {
 	DeviceID     string
 	PasswordHash string
 }
+
+type RateLimitInputData struct {
+	RateLimit *float64
+	RateBurst *int
+}
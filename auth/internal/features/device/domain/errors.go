@@ -4,3 +4,5 @@ import "errors"
 
 var ErrDeviceAlreadyExists = errors.New("device ID already in use")
 var ErrDeviceNotFound = errors.New("device not found")
+var ErrDeviceDisabled = errors.New("device disabled")
+var ErrInvalidRateLimit = errors.New("rate limit must not be negative and burst must be positive")
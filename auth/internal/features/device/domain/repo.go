@@ -4,4 +4,8 @@ type Repository interface {
 	CreateDevice(deviceInputData DeviceInputData) (uint, error)
 	DeviceExists(deviceID string) (bool, error)
 	GetDeviceByDeviceID(deviceID string) (Device, error)
+	GetRateLimitOverrides() ([]Device, error)
+	SetRateLimit(deviceID string, rateLimitInputData RateLimitInputData) error
+	GetDisabledDeviceIDs() ([]string, error)
+	SetDisabled(deviceID string, disabled bool) error
 }
@@ -48,9 +48,74 @@ func (a *AuthHandler) GetPublicKey(_ context.Context, _ *pb.GetPublicKeyRequest)
 
 	return &pb.GetPublicKeyResponse{
 		PublicKey: publicKey,
+		KeyId:     a.app.JWTManager.SigningKeyID(),
 	}, nil
 }
 
+func (a *AuthHandler) GetPublicKeys(_ context.Context, _ *pb.GetPublicKeysRequest) (*pb.GetPublicKeysResponse, error) {
+	keys := a.app.JWTManager.GetPublicKeys()
+
+	resp := &pb.GetPublicKeysResponse{
+		Keys: make([]*pb.PublicKey, 0, len(keys)),
+	}
+
+	for _, key := range keys {
+		publicKey, err := key.Encode()
+		if err != nil {
+			return nil, erax.Wrap(err, "failed to encode public key")
+		}
+
+		var retiresAt int64
+		if !key.RetiresAt.IsZero() {
+			retiresAt = key.RetiresAt.Unix()
+		}
+
+		resp.Keys = append(resp.Keys, &pb.PublicKey{
+			KeyId:     key.ID,
+			PublicKey: publicKey,
+			RetiresAt: retiresAt,
+		})
+	}
+
+	return resp, nil
+}
+
+func (a *AuthHandler) GetRateLimits(_ context.Context, _ *pb.GetRateLimitsRequest) (*pb.GetRateLimitsResponse, error) {
+	devices, err := a.app.DeviceModule.RateLimit.GetOverrides()
+	if err != nil {
+		return nil, erax.Wrap(err, "failed to get rate limit overrides")
+	}
+
+	resp := &pb.GetRateLimitsResponse{
+		Limits: make([]*pb.DeviceRateLimit, 0, len(devices)),
+	}
+
+	for _, device := range devices {
+		limit := &pb.DeviceRateLimit{
+			DeviceId: device.DeviceID,
+			Rate:     device.RateLimit,
+		}
+		if device.RateBurst != nil {
+			burst := uint32(*device.RateBurst)
+			limit.Burst = &burst
+		}
+
+		resp.Limits = append(resp.Limits, limit)
+	}
+
+	return resp, nil
+}
+
+func (a *AuthHandler) GetDisabledDevices(_ context.Context,
+	_ *pb.GetDisabledDevicesRequest) (*pb.GetDisabledDevicesResponse, error) {
+	deviceIDs, err := a.app.DeviceModule.Disable.GetDisabled()
+	if err != nil {
+		return nil, erax.Wrap(err, "failed to get disabled devices")
+	}
+
+	return &pb.GetDisabledDevicesResponse{DeviceIds: deviceIDs}, nil
+}
+
 func NewAuthHandler(app *app.App) *AuthHandler {
 	return &AuthHandler{app: app}
 }
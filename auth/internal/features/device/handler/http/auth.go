@@ -8,6 +8,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"auth/internal/app"
+	"auth/internal/features/device/domain"
 	"auth/internal/features/device/usecase"
 	"auth/internal/infra/http/handlerutil"
 	"auth/internal/shared/auth"
@@ -38,6 +39,7 @@ func Login(app *app.App) gin.HandlerFunc {
 		if err != nil {
 			handlerutil.HandleError(c, err, "failed to login device", map[error]handlerutil.ErrorResponse{
 				auth.ErrInvalidCredentials: {http.StatusUnauthorized, "Invalid credentials"},
+				domain.ErrDeviceDisabled:   {Status: http.StatusForbidden, Message: "device disabled"},
 			})
 			return
 		}
@@ -107,6 +109,11 @@ func Refresh(app *app.App) gin.HandlerFunc {
 		if err != nil {
 			zap.S().Errorf("Failed to refresh access token:\n%f", err)
 
+			if errors.Is(err, domain.ErrDeviceDisabled) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "device disabled"})
+				return
+			}
+
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
 			return
 		}
@@ -0,0 +1,101 @@
+package http
+
+import (
+	"crypto/subtle"
+	"go.uber.org/zap"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"auth/internal/app"
+	"auth/internal/features/device/domain"
+	"auth/internal/infra/http/handlerutil"
+)
+
+// BrokerAuthRequest is the body of the broker's HTTP authentication hook, e.g. EMQX's http authenticator.
+type BrokerAuthRequest struct {
+	ClientID string `json:"clientid"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// BrokerACLRequest is the body of the broker's HTTP authorization hook.
+type BrokerACLRequest struct {
+	ClientID string `json:"clientid"`
+	Username string `json:"username"`
+	Topic    string `json:"topic" binding:"required"`
+	Action   string `json:"action" binding:"required"`
+}
+
+// BrokerHookSecretHeader carries MQTT_HOOK_SECRET in the broker's hook requests.
+const BrokerHookSecretHeader = "X-Hook-Secret"
+
+// BrokerHookAuth lets only the broker call the hooks, as they share the public listener: a client that could call
+// /mqtt/auth would learn whether any credentials are valid.
+func BrokerHookAuth(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret := app.Config.MQTTHookSecret
+		if secret == "" {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader(BrokerHookSecretHeader)), []byte(secret)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// BrokerAuthenticate answers in the EMQX format; the broker treats any non-200 response as "ignore".
+func BrokerAuthenticate(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req BrokerAuthRequest
+		if !handlerutil.BindJSON(c, &req, "failed to parse broker auth request") {
+			return
+		}
+
+		allowed, superuser, err := app.DeviceModule.Broker.Authenticate(domain.BrokerClient{
+			ClientID: req.ClientID,
+			Username: req.Username,
+			Password: req.Password,
+		})
+		if err != nil {
+			zap.L().Debug("broker authentication denied", zap.String("client_id", req.ClientID), zap.Error(err))
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"result":       brokerResult(allowed),
+			"is_superuser": superuser,
+		})
+	}
+}
+
+func BrokerAuthorize(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req BrokerACLRequest
+		if !handlerutil.BindJSON(c, &req, "failed to parse broker acl request") {
+			return
+		}
+
+		allowed := app.DeviceModule.Broker.Authorize(domain.BrokerAccess{
+			Username: req.Username,
+			Topic:    req.Topic,
+			Action:   req.Action,
+		})
+
+		c.JSON(http.StatusOK, gin.H{
+			"result": brokerResult(allowed),
+		})
+	}
+}
+
+func brokerResult(allowed bool) string {
+	if allowed {
+		return "allow"
+	}
+
+	return "deny"
+}
@@ -0,0 +1,42 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"auth/internal/app"
+	"auth/internal/features/device/domain"
+	"auth/internal/infra/http/handlerutil"
+)
+
+// SetDisabledRequest disables a device, or re-enables it with false.
+type SetDisabledRequest struct {
+	Disabled *bool `json:"disabled" binding:"required"`
+}
+
+func SetDisabled(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req SetDisabledRequest
+		if !handlerutil.BindJSON(c, &req, "failed to parse set disabled request") {
+			return
+		}
+
+		err := app.DeviceModule.Disable.SetDisabled(c.Param("device_id"), *req.Disabled)
+		if err != nil {
+			handlerutil.HandleError(c, err, "failed to set disabled", map[error]handlerutil.ErrorResponse{
+				domain.ErrDeviceNotFound: {Status: http.StatusNotFound, Message: "device not found"},
+			})
+			return
+		}
+
+		message := "device has been enabled"
+		if *req.Disabled {
+			message = "device has been disabled"
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": message,
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"auth/internal/app"
+	"auth/internal/features/device/domain"
+	"auth/internal/infra/http/handlerutil"
+)
+
+// SetRateLimitRequest overrides the ingress rate limit of a device; omitted fields fall back to the ingress defaults.
+type SetRateLimitRequest struct {
+	RateLimit *float64 `json:"rate_limit"` // messages per second, 0 for unlimited
+	RateBurst *int     `json:"rate_burst"`
+}
+
+func SetRateLimit(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req SetRateLimitRequest
+		if !handlerutil.BindJSON(c, &req, "failed to parse set rate limit request") {
+			return
+		}
+
+		err := app.DeviceModule.RateLimit.SetRateLimit(c.Param("device_id"), domain.RateLimitInputData{
+			RateLimit: req.RateLimit,
+			RateBurst: req.RateBurst,
+		})
+		if err != nil {
+			handlerutil.HandleError(c, err, "failed to set rate limit", map[error]handlerutil.ErrorResponse{
+				domain.ErrDeviceNotFound:   {Status: http.StatusNotFound, Message: "device not found"},
+				domain.ErrInvalidRateLimit: {Status: http.StatusBadRequest, Message: "invalid input"},
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "rate limit has been set",
+		})
+	}
+}
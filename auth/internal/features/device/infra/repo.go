@@ -62,6 +62,61 @@ func (dr *DeviceRepo) GetDeviceByDeviceID(deviceID string) (domain.Device, error
 	return device, nil
 }
 
+func (dr *DeviceRepo) GetRateLimitOverrides() ([]domain.Device, error) {
+	var devices []domain.Device
+
+	err := dr.db.Select("device_id", "rate_limit", "rate_burst").
+		Where("rate_limit IS NOT NULL OR rate_burst IS NOT NULL").
+		Find(&devices).Error
+	if err != nil {
+		return nil, erax.Wrap(err, "failed to query rate limit overrides")
+	}
+
+	return devices, nil
+}
+
+func (dr *DeviceRepo) SetRateLimit(deviceID string, rateLimitInputData domain.RateLimitInputData) error {
+	result := dr.db.Model(&domain.Device{}).
+		Where("device_id = ?", deviceID).
+		Updates(map[string]any{
+			"rate_limit": rateLimitInputData.RateLimit,
+			"rate_burst": rateLimitInputData.RateBurst,
+		})
+	if result.Error != nil {
+		return erax.Wrap(result.Error, "failed to update rate limit")
+	}
+	if result.RowsAffected == 0 {
+		return erax.WrapWithError(gorm.ErrRecordNotFound, domain.ErrDeviceNotFound, "failed to update rate limit")
+	}
+
+	return nil
+}
+
+func (dr *DeviceRepo) GetDisabledDeviceIDs() ([]string, error) {
+	var deviceIDs []string
+
+	err := dr.db.Model(&domain.Device{}).Where("disabled = ?", true).Pluck("device_id", &deviceIDs).Error
+	if err != nil {
+		return nil, erax.Wrap(err, "failed to query disabled devices")
+	}
+
+	return deviceIDs, nil
+}
+
+func (dr *DeviceRepo) SetDisabled(deviceID string, disabled bool) error {
+	result := dr.db.Model(&domain.Device{}).
+		Where("device_id = ?", deviceID).
+		Update("disabled", disabled)
+	if result.Error != nil {
+		return erax.Wrap(result.Error, "failed to update disabled")
+	}
+	if result.RowsAffected == 0 {
+		return erax.WrapWithError(gorm.ErrRecordNotFound, domain.ErrDeviceNotFound, "failed to update disabled")
+	}
+
+	return nil
+}
+
 func NewDeviceRepo(db *gorm.DB) *DeviceRepo {
 	return &DeviceRepo{db: db}
 }
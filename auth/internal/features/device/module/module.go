@@ -7,13 +7,19 @@ import (
 )
 
 type Module struct {
-	Auth   *usecase.AuthUseCase
-	Device *usecase.DeviceUseCase
+	Auth      *usecase.AuthUseCase
+	Broker    *usecase.BrokerUseCase
+	Device    *usecase.DeviceUseCase
+	Disable   *usecase.DisableUseCase
+	RateLimit *usecase.RateLimitUseCase
 }
 
-func NewModule(repo domain.Repository, tokenGenerator token.Manager) *Module {
+func NewModule(repo domain.Repository, tokenGenerator token.Manager, brokerService domain.BrokerCredentials) *Module {
 	return &Module{
-		Auth:   usecase.NewAuthUseCase(repo, tokenGenerator),
-		Device: usecase.NewDeviceUseCase(repo),
+		Auth:      usecase.NewAuthUseCase(repo, tokenGenerator),
+		Broker:    usecase.NewBrokerUseCase(repo, tokenGenerator, brokerService),
+		Device:    usecase.NewDeviceUseCase(repo),
+		Disable:   usecase.NewDisableUseCase(repo),
+		RateLimit: usecase.NewRateLimitUseCase(repo),
 	}
 }
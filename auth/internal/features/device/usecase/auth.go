@@ -48,13 +48,16 @@ func (a *AuthUseCase) Login(accessTokenTTL, refreshTokenTTL time.Duration, reque
 	if !isValid {
 		return "", "", erax.Wrap(auth.ErrInvalidCredentials, "failed to verify password")
 	}
+	if device.Disabled {
+		return "", "", domain.ErrDeviceDisabled
+	}
 
-	accessToken, err := a.tokenManager.Generate(device.ID, "access", accessTokenTTL)
+	accessToken, err := a.tokenManager.GenerateDevice(device.ID, device.DeviceID, "access", accessTokenTTL)
 	if err != nil {
 		return "", "", erax.Wrap(err, "failed to generate token")
 	}
 
-	refreshToken, err := a.tokenManager.Generate(device.ID, "refresh", refreshTokenTTL)
+	refreshToken, err := a.tokenManager.GenerateDevice(device.ID, device.DeviceID, "refresh", refreshTokenTTL)
 	if err != nil {
 		return "", "", erax.Wrap(err, "failed to generate refresh token")
 	}
@@ -82,12 +85,12 @@ func (a *AuthUseCase) Register(accessTokenTTL, refreshTokenTTL time.Duration, re
 		return "", "", erax.WrapWithError(err, auth.ErrInvalidCredentials, "failed to create device")
 	}
 
-	accessToken, err := a.tokenManager.Generate(deviceID, "access", accessTokenTTL)
+	accessToken, err := a.tokenManager.GenerateDevice(deviceID, request.DeviceID, "access", accessTokenTTL)
 	if err != nil {
 		return "", "", erax.Wrap(err, "failed to generate token")
 	}
 
-	refreshToken, err := a.tokenManager.Generate(deviceID, "refresh", refreshTokenTTL)
+	refreshToken, err := a.tokenManager.GenerateDevice(deviceID, request.DeviceID, "refresh", refreshTokenTTL)
 	if err != nil {
 		return "", "", erax.Wrap(err, "failed to generate refresh token")
 	}
@@ -96,15 +99,23 @@ func (a *AuthUseCase) Register(accessTokenTTL, refreshTokenTTL time.Duration, re
 }
 
 func (a *AuthUseCase) Refresh(accessTokenTTL time.Duration, refreshToken string) (accessToken string, err error) {
-	deviceID, tokenType, err := a.tokenManager.ParseToken(refreshToken)
+	claims, err := a.tokenManager.ParseDeviceToken(refreshToken)
 	if err != nil {
 		return "", erax.Wrap(err, "failed to parse token")
 	}
-	if tokenType != "refresh" {
+	if claims.Type != "refresh" {
 		return "", errors.New("token is not a refresh token")
 	}
 
-	accessToken, err = a.tokenManager.Generate(deviceID, "access", accessTokenTTL)
+	device, err := a.repo.GetDeviceByDeviceID(claims.DeviceID)
+	if err != nil {
+		return "", erax.Wrap(err, "failed to get device by device ID")
+	}
+	if device.Disabled {
+		return "", domain.ErrDeviceDisabled
+	}
+
+	accessToken, err = a.tokenManager.GenerateDevice(claims.ID, claims.DeviceID, "access", accessTokenTTL)
 	if err != nil {
 		return "", erax.Wrap(err, "failed to generate access token")
 	}
@@ -0,0 +1,102 @@
+package usecase
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strings"
+
+	"github.com/DangeL187/erax"
+
+	"auth/internal/features/device/domain"
+	"auth/internal/shared/token"
+)
+
+const telemetryTopic = "devices/telemetry"
+
+type brokerRepo interface {
+	GetDeviceByDeviceID(deviceID string) (domain.Device, error)
+}
+
+// BrokerUseCase backs the MQTT broker's authentication and authorization hooks: a device connects with its
+// device ID as username and its access token as password, and may only use its own devices/<id>/ namespace
+// besides publishing telemetry.
+type BrokerUseCase struct {
+	repo         brokerRepo
+	tokenManager token.Manager
+	service      domain.BrokerCredentials
+}
+
+// Authenticate reports whether the client may connect and whether it is a superuser exempt from topic checks.
+func (b *BrokerUseCase) Authenticate(client domain.BrokerClient) (allowed bool, superuser bool, err error) {
+	if b.isService(client.Username, client.Password) {
+		return true, true, nil
+	}
+
+	if !isValidDeviceID(client.Username) || client.ClientID != client.Username {
+		return false, false, errors.New("client ID and username must be the device ID")
+	}
+
+	claims, err := b.tokenManager.ParseDeviceToken(client.Password)
+	if err != nil {
+		return false, false, erax.Wrap(err, "failed to parse token")
+	}
+	if claims.Type != "access" {
+		return false, false, errors.New("token is not an access token")
+	}
+	if claims.DeviceID != client.Username {
+		return false, false, errors.New("token belongs to another device")
+	}
+
+	device, err := b.repo.GetDeviceByDeviceID(claims.DeviceID)
+	if err != nil {
+		return false, false, erax.Wrap(err, "failed to get device by device ID")
+	}
+	if device.Disabled {
+		return false, false, domain.ErrDeviceDisabled
+	}
+
+	return true, false, nil
+}
+
+// Authorize reports whether a device may publish or subscribe to a topic; superusers never reach it.
+func (b *BrokerUseCase) Authorize(access domain.BrokerAccess) bool {
+	if !isValidDeviceID(access.Username) || access.Username == b.service.Username {
+		return false
+	}
+
+	ownPrefix := "devices/" + access.Username + "/"
+	switch access.Action {
+	case domain.BrokerActionPublish:
+		if strings.ContainsAny(access.Topic, "+#") {
+			return false
+		}
+		return access.Topic == telemetryTopic ||
+			strings.HasPrefix(access.Topic, telemetryTopic+"/") ||
+			strings.HasPrefix(access.Topic, ownPrefix)
+	case domain.BrokerActionSubscribe:
+		return strings.HasPrefix(access.Topic, ownPrefix)
+	default:
+		return false
+	}
+}
+
+func (b *BrokerUseCase) isService(username, password string) bool {
+	if b.service.Password == "" || username != b.service.Username {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(password), []byte(b.service.Password)) == 1
+}
+
+// isValidDeviceID rejects IDs that would escape the device's topic namespace, e.g. "+" or "a/b".
+func isValidDeviceID(deviceID string) bool {
+	return deviceID != "" && deviceID != "telemetry" && !strings.ContainsAny(deviceID, "/+#")
+}
+
+func NewBrokerUseCase(repo brokerRepo, tokenManager token.Manager, service domain.BrokerCredentials) *BrokerUseCase {
+	return &BrokerUseCase{
+		repo:         repo,
+		tokenManager: tokenManager,
+		service:      service,
+	}
+}
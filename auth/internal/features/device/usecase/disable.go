@@ -0,0 +1,27 @@
+package usecase
+
+type disableRepo interface {
+	GetDisabledDeviceIDs() ([]string, error)
+	SetDisabled(deviceID string, disabled bool) error
+}
+
+type DisableUseCase struct {
+	repo disableRepo
+}
+
+// GetDisabled returns the IDs of the disabled devices, for ingress to reject their messages.
+func (d *DisableUseCase) GetDisabled() ([]string, error) {
+	return d.repo.GetDisabledDeviceIDs()
+}
+
+// SetDisabled disables or re-enables a device. A disabled device keeps an open broker connection until it
+// reconnects, but ingress rejects its messages once it has picked up the change.
+func (d *DisableUseCase) SetDisabled(deviceID string, disabled bool) error {
+	return d.repo.SetDisabled(deviceID, disabled)
+}
+
+func NewDisableUseCase(repo disableRepo) *DisableUseCase {
+	return &DisableUseCase{
+		repo: repo,
+	}
+}
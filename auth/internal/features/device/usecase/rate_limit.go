@@ -0,0 +1,34 @@
+package usecase
+
+import (
+	"auth/internal/features/device/domain"
+)
+
+type rateLimitRepo interface {
+	GetRateLimitOverrides() ([]domain.Device, error)
+	SetRateLimit(deviceID string, rateLimitInputData domain.RateLimitInputData) error
+}
+
+type RateLimitUseCase struct {
+	repo rateLimitRepo
+}
+
+// GetOverrides returns the devices whose rate limit differs from the ingress defaults.
+func (r *RateLimitUseCase) GetOverrides() ([]domain.Device, error) {
+	return r.repo.GetRateLimitOverrides()
+}
+
+// SetRateLimit stores a device's rate limit override; nil fields reset it to the ingress defaults.
+func (r *RateLimitUseCase) SetRateLimit(deviceID string, input domain.RateLimitInputData) error {
+	if (input.RateLimit != nil && *input.RateLimit < 0) || (input.RateBurst != nil && *input.RateBurst <= 0) {
+		return domain.ErrInvalidRateLimit
+	}
+
+	return r.repo.SetRateLimit(deviceID, input)
+}
+
+func NewRateLimitUseCase(repo rateLimitRepo) *RateLimitUseCase {
+	return &RateLimitUseCase{
+		repo: repo,
+	}
+}
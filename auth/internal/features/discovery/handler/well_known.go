@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"auth/internal/app"
+	"auth/internal/infra/jwt"
+)
+
+type jwksResponse struct {
+	Keys []jwt.JWK `json:"keys"`
+}
+
+func JWKS(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keys := app.JWTManager.GetPublicKeys()
+
+		resp := jwksResponse{
+			Keys: make([]jwt.JWK, 0, len(keys)),
+		}
+		for _, key := range keys {
+			resp.Keys = append(resp.Keys, key.JWK())
+		}
+
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+type openIDConfigurationResponse struct {
+	Issuer                           string   `json:"issuer"`
+	JWKSURI                          string   `json:"jwks_uri"`
+	ResponseTypesSupported           []string `json:"response_types_supported"`
+	SubjectTypesSupported            []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+	ClaimsSupported                  []string `json:"claims_supported"`
+}
+
+func OpenIDConfiguration(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		issuer := app.JWTManager.Issuer()
+
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, openIDConfigurationResponse{
+			Issuer:                           issuer,
+			JWKSURI:                          strings.TrimSuffix(issuer, "/") + "/.well-known/jwks.json",
+			ResponseTypesSupported:           []string{"token"},
+			SubjectTypesSupported:            []string{"public"},
+			IDTokenSigningAlgValuesSupported: []string{"EdDSA"},
+			ClaimsSupported:                  []string{"iss", "aud", "sub", "exp", "iat", "typ", "device_id"},
+		})
+	}
+}
@@ -156,6 +156,7 @@ func (*GetPublicKeyRequest) Descriptor() ([]byte, []int) {
 type GetPublicKeyResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	PublicKey     string                 `protobuf:"bytes,1,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	KeyId         string                 `protobuf:"bytes,2,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -197,6 +198,374 @@ func (x *GetPublicKeyResponse) GetPublicKey() string {
 	return ""
 }
 
+func (x *GetPublicKeyResponse) GetKeyId() string {
+	if x != nil {
+		return x.KeyId
+	}
+	return ""
+}
+
+type GetPublicKeysRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPublicKeysRequest) Reset() {
+	*x = GetPublicKeysRequest{}
+	mi := &file_auth_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPublicKeysRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPublicKeysRequest) ProtoMessage() {}
+
+func (x *GetPublicKeysRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_auth_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPublicKeysRequest.ProtoReflect.Descriptor instead.
+func (*GetPublicKeysRequest) Descriptor() ([]byte, []int) {
+	return file_auth_proto_rawDescGZIP(), []int{4}
+}
+
+type PublicKey struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	KeyId         string                 `protobuf:"bytes,1,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`
+	PublicKey     string                 `protobuf:"bytes,2,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	RetiresAt     int64                  `protobuf:"varint,3,opt,name=retires_at,json=retiresAt,proto3" json:"retires_at,omitempty"` // unix seconds, 0 if the key never retires
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PublicKey) Reset() {
+	*x = PublicKey{}
+	mi := &file_auth_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PublicKey) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PublicKey) ProtoMessage() {}
+
+func (x *PublicKey) ProtoReflect() protoreflect.Message {
+	mi := &file_auth_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PublicKey.ProtoReflect.Descriptor instead.
+func (*PublicKey) Descriptor() ([]byte, []int) {
+	return file_auth_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *PublicKey) GetKeyId() string {
+	if x != nil {
+		return x.KeyId
+	}
+	return ""
+}
+
+func (x *PublicKey) GetPublicKey() string {
+	if x != nil {
+		return x.PublicKey
+	}
+	return ""
+}
+
+func (x *PublicKey) GetRetiresAt() int64 {
+	if x != nil {
+		return x.RetiresAt
+	}
+	return 0
+}
+
+type GetPublicKeysResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Keys          []*PublicKey           `protobuf:"bytes,1,rep,name=keys,proto3" json:"keys,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPublicKeysResponse) Reset() {
+	*x = GetPublicKeysResponse{}
+	mi := &file_auth_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPublicKeysResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPublicKeysResponse) ProtoMessage() {}
+
+func (x *GetPublicKeysResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_auth_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPublicKeysResponse.ProtoReflect.Descriptor instead.
+func (*GetPublicKeysResponse) Descriptor() ([]byte, []int) {
+	return file_auth_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetPublicKeysResponse) GetKeys() []*PublicKey {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+type GetRateLimitsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRateLimitsRequest) Reset() {
+	*x = GetRateLimitsRequest{}
+	mi := &file_auth_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRateLimitsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRateLimitsRequest) ProtoMessage() {}
+
+func (x *GetRateLimitsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_auth_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRateLimitsRequest.ProtoReflect.Descriptor instead.
+func (*GetRateLimitsRequest) Descriptor() ([]byte, []int) {
+	return file_auth_proto_rawDescGZIP(), []int{7}
+}
+
+// DeviceRateLimit overrides the ingress defaults for one device; unset fields keep the default.
+type DeviceRateLimit struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DeviceId      string                 `protobuf:"bytes,1,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	Rate          *float64               `protobuf:"fixed64,2,opt,name=rate,proto3,oneof" json:"rate,omitempty"` // messages per second, 0 for unlimited
+	Burst         *uint32                `protobuf:"varint,3,opt,name=burst,proto3,oneof" json:"burst,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeviceRateLimit) Reset() {
+	*x = DeviceRateLimit{}
+	mi := &file_auth_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeviceRateLimit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeviceRateLimit) ProtoMessage() {}
+
+func (x *DeviceRateLimit) ProtoReflect() protoreflect.Message {
+	mi := &file_auth_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeviceRateLimit.ProtoReflect.Descriptor instead.
+func (*DeviceRateLimit) Descriptor() ([]byte, []int) {
+	return file_auth_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *DeviceRateLimit) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+func (x *DeviceRateLimit) GetRate() float64 {
+	if x != nil && x.Rate != nil {
+		return *x.Rate
+	}
+	return 0
+}
+
+func (x *DeviceRateLimit) GetBurst() uint32 {
+	if x != nil && x.Burst != nil {
+		return *x.Burst
+	}
+	return 0
+}
+
+type GetRateLimitsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Limits        []*DeviceRateLimit     `protobuf:"bytes,1,rep,name=limits,proto3" json:"limits,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRateLimitsResponse) Reset() {
+	*x = GetRateLimitsResponse{}
+	mi := &file_auth_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRateLimitsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRateLimitsResponse) ProtoMessage() {}
+
+func (x *GetRateLimitsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_auth_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRateLimitsResponse.ProtoReflect.Descriptor instead.
+func (*GetRateLimitsResponse) Descriptor() ([]byte, []int) {
+	return file_auth_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetRateLimitsResponse) GetLimits() []*DeviceRateLimit {
+	if x != nil {
+		return x.Limits
+	}
+	return nil
+}
+
+type GetDisabledDevicesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDisabledDevicesRequest) Reset() {
+	*x = GetDisabledDevicesRequest{}
+	mi := &file_auth_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDisabledDevicesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDisabledDevicesRequest) ProtoMessage() {}
+
+func (x *GetDisabledDevicesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_auth_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDisabledDevicesRequest.ProtoReflect.Descriptor instead.
+func (*GetDisabledDevicesRequest) Descriptor() ([]byte, []int) {
+	return file_auth_proto_rawDescGZIP(), []int{10}
+}
+
+type GetDisabledDevicesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DeviceIds     []string               `protobuf:"bytes,1,rep,name=device_ids,json=deviceIds,proto3" json:"device_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDisabledDevicesResponse) Reset() {
+	*x = GetDisabledDevicesResponse{}
+	mi := &file_auth_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDisabledDevicesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDisabledDevicesResponse) ProtoMessage() {}
+
+func (x *GetDisabledDevicesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_auth_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDisabledDevicesResponse.ProtoReflect.Descriptor instead.
+func (*GetDisabledDevicesResponse) Descriptor() ([]byte, []int) {
+	return file_auth_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GetDisabledDevicesResponse) GetDeviceIds() []string {
+	if x != nil {
+		return x.DeviceIds
+	}
+	return nil
+}
+
 var File_auth_proto protoreflect.FileDescriptor
 
 const file_auth_proto_rawDesc = "" +
@@ -208,14 +577,40 @@ const file_auth_proto_rawDesc = "" +
 	"\x12AuthDeviceResponse\x12\x1b\n" +
 	"\tdevice_id\x18\x01 \x01(\x04R\bdeviceId\x12\x14\n" +
 	"\x05error\x18\x02 \x01(\tR\x05error\"\x15\n" +
-	"\x13GetPublicKeyRequest\"5\n" +
+	"\x13GetPublicKeyRequest\"L\n" +
 	"\x14GetPublicKeyResponse\x12\x1d\n" +
 	"\n" +
-	"public_key\x18\x01 \x01(\tR\tpublicKey2\x95\x01\n" +
+	"public_key\x18\x01 \x01(\tR\tpublicKey\x12\x15\n" +
+	"\x06key_id\x18\x02 \x01(\tR\x05keyId\"\x16\n" +
+	"\x14GetPublicKeysRequest\"`\n" +
+	"\tPublicKey\x12\x15\n" +
+	"\x06key_id\x18\x01 \x01(\tR\x05keyId\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\x02 \x01(\tR\tpublicKey\x12\x1d\n" +
+	"\n" +
+	"retires_at\x18\x03 \x01(\x03R\tretiresAt\"<\n" +
+	"\x15GetPublicKeysResponse\x12#\n" +
+	"\x04keys\x18\x01 \x03(\v2\x0f.auth.PublicKeyR\x04keys\"\x16\n" +
+	"\x14GetRateLimitsRequest\"u\n" +
+	"\x0fDeviceRateLimit\x12\x1b\n" +
+	"\tdevice_id\x18\x01 \x01(\tR\bdeviceId\x12\x17\n" +
+	"\x04rate\x18\x02 \x01(\x01H\x00R\x04rate\x88\x01\x01\x12\x19\n" +
+	"\x05burst\x18\x03 \x01(\rH\x01R\x05burst\x88\x01\x01B\a\n" +
+	"\x05_rateB\b\n" +
+	"\x06_burst\"F\n" +
+	"\x15GetRateLimitsResponse\x12-\n" +
+	"\x06limits\x18\x01 \x03(\v2\x15.auth.DeviceRateLimitR\x06limits\"\x1b\n" +
+	"\x19GetDisabledDevicesRequest\";\n" +
+	"\x1aGetDisabledDevicesResponse\x12\x1d\n" +
+	"\n" +
+	"device_ids\x18\x01 \x03(\tR\tdeviceIds2\x82\x03\n" +
 	"\vAuthService\x12?\n" +
 	"\n" +
 	"AuthDevice\x12\x17.auth.AuthDeviceRequest\x1a\x18.auth.AuthDeviceResponse\x12E\n" +
-	"\fGetPublicKey\x12\x19.auth.GetPublicKeyRequest\x1a\x1a.auth.GetPublicKeyResponseB\tZ\a.;protob\x06proto3"
+	"\fGetPublicKey\x12\x19.auth.GetPublicKeyRequest\x1a\x1a.auth.GetPublicKeyResponse\x12H\n" +
+	"\rGetPublicKeys\x12\x1a.auth.GetPublicKeysRequest\x1a\x1b.auth.GetPublicKeysResponse\x12H\n" +
+	"\rGetRateLimits\x12\x1a.auth.GetRateLimitsRequest\x1a\x1b.auth.GetRateLimitsResponse\x12W\n" +
+	"\x12GetDisabledDevices\x12\x1f.auth.GetDisabledDevicesRequest\x1a .auth.GetDisabledDevicesResponseB\tZ\a.;protob\x06proto3"
 
 var (
 	file_auth_proto_rawDescOnce sync.Once
@@ -229,23 +624,39 @@ func file_auth_proto_rawDescGZIP() []byte {
 	return file_auth_proto_rawDescData
 }
 
-var file_auth_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_auth_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
 var file_auth_proto_goTypes = []any{
-	(*AuthDeviceRequest)(nil),    // 0: auth.AuthDeviceRequest
-	(*AuthDeviceResponse)(nil),   // 1: auth.AuthDeviceResponse
-	(*GetPublicKeyRequest)(nil),  // 2: auth.GetPublicKeyRequest
-	(*GetPublicKeyResponse)(nil), // 3: auth.GetPublicKeyResponse
+	(*AuthDeviceRequest)(nil),          // 0: auth.AuthDeviceRequest
+	(*AuthDeviceResponse)(nil),         // 1: auth.AuthDeviceResponse
+	(*GetPublicKeyRequest)(nil),        // 2: auth.GetPublicKeyRequest
+	(*GetPublicKeyResponse)(nil),       // 3: auth.GetPublicKeyResponse
+	(*GetPublicKeysRequest)(nil),       // 4: auth.GetPublicKeysRequest
+	(*PublicKey)(nil),                  // 5: auth.PublicKey
+	(*GetPublicKeysResponse)(nil),      // 6: auth.GetPublicKeysResponse
+	(*GetRateLimitsRequest)(nil),       // 7: auth.GetRateLimitsRequest
+	(*DeviceRateLimit)(nil),            // 8: auth.DeviceRateLimit
+	(*GetRateLimitsResponse)(nil),      // 9: auth.GetRateLimitsResponse
+	(*GetDisabledDevicesRequest)(nil),  // 10: auth.GetDisabledDevicesRequest
+	(*GetDisabledDevicesResponse)(nil), // 11: auth.GetDisabledDevicesResponse
 }
 var file_auth_proto_depIdxs = []int32{
-	0, // 0: auth.AuthService.AuthDevice:input_type -> auth.AuthDeviceRequest
-	2, // 1: auth.AuthService.GetPublicKey:input_type -> auth.GetPublicKeyRequest
-	1, // 2: auth.AuthService.AuthDevice:output_type -> auth.AuthDeviceResponse
-	3, // 3: auth.AuthService.GetPublicKey:output_type -> auth.GetPublicKeyResponse
-	2, // [2:4] is the sub-list for method output_type
-	0, // [0:2] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	5,  // 0: auth.GetPublicKeysResponse.keys:type_name -> auth.PublicKey
+	8,  // 1: auth.GetRateLimitsResponse.limits:type_name -> auth.DeviceRateLimit
+	0,  // 2: auth.AuthService.AuthDevice:input_type -> auth.AuthDeviceRequest
+	2,  // 3: auth.AuthService.GetPublicKey:input_type -> auth.GetPublicKeyRequest
+	4,  // 4: auth.AuthService.GetPublicKeys:input_type -> auth.GetPublicKeysRequest
+	7,  // 5: auth.AuthService.GetRateLimits:input_type -> auth.GetRateLimitsRequest
+	10, // 6: auth.AuthService.GetDisabledDevices:input_type -> auth.GetDisabledDevicesRequest
+	1,  // 7: auth.AuthService.AuthDevice:output_type -> auth.AuthDeviceResponse
+	3,  // 8: auth.AuthService.GetPublicKey:output_type -> auth.GetPublicKeyResponse
+	6,  // 9: auth.AuthService.GetPublicKeys:output_type -> auth.GetPublicKeysResponse
+	9,  // 10: auth.AuthService.GetRateLimits:output_type -> auth.GetRateLimitsResponse
+	11, // 11: auth.AuthService.GetDisabledDevices:output_type -> auth.GetDisabledDevicesResponse
+	7,  // [7:12] is the sub-list for method output_type
+	2,  // [2:7] is the sub-list for method input_type
+	2,  // [2:2] is the sub-list for extension type_name
+	2,  // [2:2] is the sub-list for extension extendee
+	0,  // [0:2] is the sub-list for field type_name
 }
 
 func init() { file_auth_proto_init() }
@@ -253,13 +664,14 @@ func file_auth_proto_init() {
 	if File_auth_proto != nil {
 		return
 	}
+	file_auth_proto_msgTypes[8].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_auth_proto_rawDesc), len(file_auth_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   4,
+			NumMessages:   12,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
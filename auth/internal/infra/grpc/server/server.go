@@ -3,6 +3,7 @@ package server
 import (
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"net"
 
 	"github.com/DangeL187/erax"
@@ -31,10 +32,21 @@ func (s *Server) Run(addr string) error {
 	return nil
 }
 
-func NewServer(app *app.App) *Server {
-	grpcServer := grpc.NewServer()
+func NewServer(app *app.App) (*Server, error) {
+	var opts []grpc.ServerOption
+
+	// with a CA bundle configured, ingress must authenticate with a client certificate (mTLS)
+	tlsConfig, err := app.Config.GRPCTLS.ServerTLS()
+	if err != nil {
+		return nil, erax.Wrap(err, "failed to load gRPC tls config")
+	}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	grpcServer := grpc.NewServer(opts...)
 
 	pb.RegisterAuthServiceServer(grpcServer, handler.NewAuthHandler(app))
 
-	return &Server{grpcServer: grpcServer}
+	return &Server{grpcServer: grpcServer}, nil
 }
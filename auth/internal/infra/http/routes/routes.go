@@ -5,11 +5,22 @@ import (
 
 	"auth/internal/app"
 	deviceHandler "auth/internal/features/device/handler/http"
+	discoveryHandler "auth/internal/features/discovery/handler"
 	userHandler "auth/internal/features/user/handler"
 	"auth/internal/features/user/middleware"
 )
 
 func SetupRoutes(router *gin.Engine, app *app.App) {
+	router.GET(
+		"/.well-known/jwks.json",
+		discoveryHandler.JWKS(app),
+	)
+
+	router.GET(
+		"/.well-known/openid-configuration",
+		discoveryHandler.OpenIDConfiguration(app),
+	)
+
 	router.POST(
 		"/users/login",
 		userHandler.Login(app),
@@ -64,4 +75,30 @@ func SetupRoutes(router *gin.Engine, app *app.App) {
 		"/devices/refresh",
 		deviceHandler.Refresh(app),
 	)
+
+	router.PUT(
+		"/devices/:device_id/rate_limit",
+		middleware.Auth(app),
+		middleware.UserHasPermission(app, "device", "rate_limit"),
+		deviceHandler.SetRateLimit(app),
+	)
+
+	router.PUT(
+		"/devices/:device_id/disabled",
+		middleware.Auth(app),
+		middleware.UserHasPermission(app, "device", "disable"),
+		deviceHandler.SetDisabled(app),
+	)
+
+	router.POST(
+		"/mqtt/auth",
+		deviceHandler.BrokerHookAuth(app),
+		deviceHandler.BrokerAuthenticate(app),
+	)
+
+	router.POST(
+		"/mqtt/acl",
+		deviceHandler.BrokerHookAuth(app),
+		deviceHandler.BrokerAuthorize(app),
+	)
 }
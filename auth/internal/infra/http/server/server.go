@@ -1,7 +1,9 @@
 package server
 
 import (
+	"crypto/tls"
 	"go.uber.org/zap"
+	"net/http"
 
 	"github.com/DangeL187/erax"
 	"github.com/gin-gonic/gin"
@@ -11,25 +13,49 @@ import (
 )
 
 type Server struct {
-	engine *gin.Engine
+	engine    *gin.Engine
+	tlsConfig *tls.Config
 }
 
 func (s *Server) Run(addr string) error {
-	zap.S().Infof("HTTP server launched on http://%s", addr)
+	if s.tlsConfig == nil {
+		zap.S().Infof("HTTP server launched on http://%s", addr)
 
-	err := s.engine.Run(addr)
+		err := s.engine.Run(addr)
+		if err != nil {
+			return erax.Wrap(err, "failed to start HTTP server")
+		}
+
+		return nil
+	}
+
+	zap.S().Infof("HTTP server launched on https://%s", addr)
+
+	httpServer := &http.Server{
+		Addr:      addr,
+		Handler:   s.engine.Handler(),
+		TLSConfig: s.tlsConfig,
+	}
+
+	// the certificate is already in TLSConfig
+	err := httpServer.ListenAndServeTLS("", "")
 	if err != nil {
-		return erax.Wrap(err, "failed to start HTTP server")
+		return erax.Wrap(err, "failed to start HTTPS server")
 	}
 
 	return nil
 }
 
-func NewServer(app *app.App) *Server {
+func NewServer(app *app.App) (*Server, error) {
+	tlsConfig, err := app.Config.HTTPTLS.ServerTLS()
+	if err != nil {
+		return nil, erax.Wrap(err, "failed to load HTTP tls config")
+	}
+
 	engine := gin.New()
 	engine.Use(gin.Recovery(), gin.Logger())
 
 	routes.SetupRoutes(engine, app)
 
-	return &Server{engine: engine}
+	return &Server{engine: engine, tlsConfig: tlsConfig}, nil
 }
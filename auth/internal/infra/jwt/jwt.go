@@ -2,23 +2,30 @@ package jwt
 
 import (
 	"crypto/ed25519"
-	"crypto/x509"
-	"encoding/base64"
 	"errors"
 	"os"
 	"time"
 
 	"github.com/DangeL187/erax"
 	"github.com/golang-jwt/jwt/v5"
+
+	"auth/internal/shared/config"
+	"auth/internal/shared/token"
 )
 
 type Manager struct {
-	privateKey ed25519.PrivateKey
-	publicKey  ed25519.PublicKey
+	audience string
+	issuer   string
+
+	signingKeyID string
+	privateKey   ed25519.PrivateKey
+	publicKeys   map[string]PublicKey
 }
 
 func (m *Manager) Generate(id uint, tokenType string, ttl time.Duration) (string, error) {
 	claims := jwt.MapClaims{
+		"iss": m.issuer,
+		"aud": m.audience,
 		"sub": id,
 		"typ": tokenType,
 		"exp": time.Now().Add(ttl).Unix(),
@@ -26,35 +33,146 @@ func (m *Manager) Generate(id uint, tokenType string, ttl time.Duration) (string
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = m.signingKeyID
+	return token.SignedString(m.privateKey)
+}
+
+func (m *Manager) GenerateDevice(id uint, deviceID, tokenType string, ttl time.Duration) (string, error) {
+	claims := jwt.MapClaims{
+		"iss":       m.issuer,
+		"aud":       m.audience,
+		"sub":       id,
+		"device_id": deviceID,
+		"typ":       tokenType,
+		"exp":       time.Now().Add(ttl).Unix(),
+		"iat":       time.Now().Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = m.signingKeyID
 	return token.SignedString(m.privateKey)
 }
 
 func (m *Manager) GetPublicKey() (string, error) {
-	pubDER, err := x509.MarshalPKIXPublicKey(m.publicKey)
+	publicKey, err := m.publicKeys[m.signingKeyID].Encode()
 	if err != nil {
-		return "", erax.Wrap(err, "failed to marshal public key")
+		return "", erax.Wrap(err, "failed to encode signing public key")
 	}
 
-	return base64.StdEncoding.EncodeToString(pubDER), nil
+	return publicKey, nil
 }
 
-func (m *Manager) ParseToken(tokenString string) (uint, string, error) {
-	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodEd25519); !ok {
-			return nil, errors.New("invalid signing method")
+// GetPublicKeys returns every key that still verifies tokens, the signing key first.
+func (m *Manager) GetPublicKeys() []PublicKey {
+	now := time.Now()
+
+	keys := []PublicKey{m.publicKeys[m.signingKeyID]}
+	for id, key := range m.publicKeys {
+		if id == m.signingKeyID || key.Retired(now) {
+			continue
 		}
-		return m.publicKey, nil
-	})
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+func (m *Manager) Issuer() string {
+	return m.issuer
+}
 
-	if err != nil || !token.Valid {
-		return 0, "", erax.Wrap(err, "invalid token")
+func (m *Manager) SigningKeyID() string {
+	return m.signingKeyID
+}
+
+func (m *Manager) ParseToken(tokenString string) (uint, string, error) {
+	claims, err := m.parseClaims(tokenString)
+	if err != nil {
+		return 0, "", erax.Wrap(err, "failed to parse claims")
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
+	return subjectAndType(claims)
+}
+
+func (m *Manager) ParseDeviceToken(tokenString string) (token.DeviceClaims, error) {
+	claims, err := m.parseClaims(tokenString)
+	if err != nil {
+		return token.DeviceClaims{}, erax.Wrap(err, "failed to parse claims")
+	}
+
+	id, typ, err := subjectAndType(claims)
+	if err != nil {
+		return token.DeviceClaims{}, err
+	}
+
+	deviceID, ok := claims["device_id"].(string)
+	if !ok || deviceID == "" {
+		return token.DeviceClaims{}, errors.New("device_id claim is missing or not a string")
+	}
+
+	return token.DeviceClaims{
+		ID:       id,
+		DeviceID: deviceID,
+		Type:     typ,
+	}, nil
+}
+
+func (m *Manager) parseClaims(tokenString string) (jwt.MapClaims, error) {
+	parsed, err := jwt.Parse(tokenString, m.verificationKey)
+
+	if err != nil || !parsed.Valid {
+		return nil, erax.Wrap(err, "invalid token")
+	}
+
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("invalid claims format")
+	}
+
+	if err = m.validateIssuerAndAudience(parsed.Header, claims); err != nil {
+		return nil, erax.Wrap(err, "invalid token")
+	}
+
+	return claims, nil
+}
+
+// validateIssuerAndAudience checks the iss and aud claims. Tokens without a kid were issued before key rotation and
+// these claims were introduced, so they are accepted without either until they expire, at most the refresh TTL after
+// the upgrade; any other token must carry both.
+func (m *Manager) validateIssuerAndAudience(header map[string]interface{}, claims jwt.MapClaims) error {
+	kid, _ := header["kid"].(string)
+	_, hasIssuer := claims["iss"]
+	_, hasAudience := claims["aud"]
+	if kid == "" && !hasIssuer && !hasAudience {
+		return nil
+	}
+
+	return jwt.NewValidator(jwt.WithIssuer(m.issuer), jwt.WithAudience(m.audience)).Validate(claims)
+}
+
+func (m *Manager) verificationKey(t *jwt.Token) (interface{}, error) {
+	if _, ok := t.Method.(*jwt.SigningMethodEd25519); !ok {
+		return nil, errors.New("invalid signing method")
+	}
+
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		// tokens issued before key rotation was introduced carry no kid
+		kid = m.signingKeyID
+	}
+
+	key, ok := m.publicKeys[kid]
 	if !ok {
-		return 0, "", errors.New("invalid claims format")
+		return nil, errors.New("unknown key ID")
+	}
+	if key.Retired(time.Now()) {
+		return nil, errors.New("key is retired")
 	}
 
+	return key.Key, nil
+}
+
+func subjectAndType(claims jwt.MapClaims) (uint, string, error) {
 	subFloat, ok := claims["sub"].(float64)
 	if !ok {
 		return 0, "", errors.New("sub claim is missing or not a number")
@@ -68,7 +186,7 @@ func (m *Manager) ParseToken(tokenString string) (uint, string, error) {
 	return uint(subFloat), typ, nil
 }
 
-func NewJWTManager() (*Manager, error) {
+func NewJWTManager(cfg *config.Config) (*Manager, error) {
 	privateB64 := os.Getenv("JWT_PRIVATE_KEY")
 	publicB64 := os.Getenv("JWT_PUBLIC_KEY")
 
@@ -76,35 +194,40 @@ func NewJWTManager() (*Manager, error) {
 		return nil, errors.New("missing JWT_PRIVATE_KEY or JWT_PUBLIC_KEY")
 	}
 
-	privateDER, err := base64.StdEncoding.DecodeString(privateB64)
+	privateKey, err := parsePrivateKey(privateB64)
 	if err != nil {
-		return nil, err
-	}
-	publicDER, err := base64.StdEncoding.DecodeString(publicB64)
-	if err != nil {
-		return nil, err
+		return nil, erax.Wrap(err, "failed to parse JWT_PRIVATE_KEY")
 	}
 
-	privateKeyIfc, err := x509.ParsePKCS8PrivateKey(privateDER)
+	publicKey, err := parsePublicKey(publicB64)
 	if err != nil {
-		return nil, err
+		return nil, erax.Wrap(err, "failed to parse JWT_PUBLIC_KEY")
 	}
-	privateKey, ok := privateKeyIfc.(ed25519.PrivateKey)
-	if !ok {
-		return nil, errors.New("not an ed25519 private key")
+	if !publicKey.Equal(privateKey.Public()) {
+		return nil, errors.New("JWT_PUBLIC_KEY does not match JWT_PRIVATE_KEY")
 	}
 
-	publicKeyIfc, err := x509.ParsePKIXPublicKey(publicDER)
+	previousKeys, err := parsePreviousPublicKeys(os.Getenv("JWT_PREVIOUS_PUBLIC_KEYS"))
 	if err != nil {
-		return nil, err
+		return nil, erax.Wrap(err, "failed to parse JWT_PREVIOUS_PUBLIC_KEYS")
 	}
-	publicKey, ok := publicKeyIfc.(ed25519.PublicKey)
-	if !ok {
-		return nil, errors.New("not an ed25519 public key")
+
+	signingKeyID := keyID(publicKey)
+
+	publicKeys := make(map[string]PublicKey, len(previousKeys)+1)
+	for _, key := range previousKeys {
+		publicKeys[key.ID] = key
+	}
+	publicKeys[signingKeyID] = PublicKey{
+		ID:  signingKeyID,
+		Key: publicKey,
 	}
 
 	return &Manager{
-		privateKey: privateKey,
-		publicKey:  publicKey,
+		audience:     cfg.JWTAudience,
+		issuer:       cfg.JWTIssuer,
+		signingKeyID: signingKeyID,
+		privateKey:   privateKey,
+		publicKeys:   publicKeys,
 	}, nil
 }
@@ -0,0 +1,131 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/DangeL187/erax"
+)
+
+type PublicKey struct {
+	ID        string
+	Key       ed25519.PublicKey
+	RetiresAt time.Time // zero value means the key never retires
+}
+
+func (k PublicKey) Retired(now time.Time) bool {
+	return !k.RetiresAt.IsZero() && !now.Before(k.RetiresAt)
+}
+
+func (k PublicKey) Encode() (string, error) {
+	pubDER, err := x509.MarshalPKIXPublicKey(k.Key)
+	if err != nil {
+		return "", erax.Wrap(err, "failed to marshal public key")
+	}
+
+	return base64.StdEncoding.EncodeToString(pubDER), nil
+}
+
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+}
+
+func (k PublicKey) JWK() JWK {
+	return JWK{
+		KeyType:   "OKP",
+		Curve:     "Ed25519",
+		X:         base64.RawURLEncoding.EncodeToString(k.Key),
+		KeyID:     k.ID,
+		Use:       "sig",
+		Algorithm: "EdDSA",
+	}
+}
+
+// keyID returns the RFC 7638 JWK thumbprint of an Ed25519 public key.
+func keyID(publicKey ed25519.PublicKey) string {
+	jwk := `{"crv":"Ed25519","kty":"OKP","x":"` + base64.RawURLEncoding.EncodeToString(publicKey) + `"}`
+	sum := sha256.Sum256([]byte(jwk))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func parsePrivateKey(privateB64 string) (ed25519.PrivateKey, error) {
+	privateDER, err := base64.StdEncoding.DecodeString(privateB64)
+	if err != nil {
+		return nil, erax.Wrap(err, "failed to decode base64 private key")
+	}
+
+	privateKeyIfc, err := x509.ParsePKCS8PrivateKey(privateDER)
+	if err != nil {
+		return nil, erax.Wrap(err, "failed to parse DER private key")
+	}
+	privateKey, ok := privateKeyIfc.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an ed25519 private key")
+	}
+
+	return privateKey, nil
+}
+
+func parsePublicKey(publicB64 string) (ed25519.PublicKey, error) {
+	publicDER, err := base64.StdEncoding.DecodeString(publicB64)
+	if err != nil {
+		return nil, erax.Wrap(err, "failed to decode base64 public key")
+	}
+
+	publicKeyIfc, err := x509.ParsePKIXPublicKey(publicDER)
+	if err != nil {
+		return nil, erax.Wrap(err, "failed to parse DER public key")
+	}
+	publicKey, ok := publicKeyIfc.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.New("not an ed25519 public key")
+	}
+
+	return publicKey, nil
+}
+
+// parsePreviousPublicKeys parses a comma-separated list of "<base64 DER public key>[@<RFC 3339 retirement time>]".
+func parsePreviousPublicKeys(value string) ([]PublicKey, error) {
+	var keys []PublicKey
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		publicB64, retiresAtStr, hasRetiresAt := strings.Cut(entry, "@")
+
+		publicKey, err := parsePublicKey(publicB64)
+		if err != nil {
+			return nil, erax.Wrap(err, "failed to parse previous public key")
+		}
+
+		key := PublicKey{
+			ID:  keyID(publicKey),
+			Key: publicKey,
+		}
+
+		if hasRetiresAt {
+			key.RetiresAt, err = time.Parse(time.RFC3339, retiresAtStr)
+			if err != nil {
+				return nil, erax.Wrap(err, "failed to parse previous public key retirement time")
+			}
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
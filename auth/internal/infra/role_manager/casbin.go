@@ -83,6 +83,8 @@ func (rm *RoleManager) loadPolicy() error {
 		{"admin", "user", "grant_role", "allow"},
 		{"admin", "user", "revoke_role", "allow"},
 		{"operator", "device", "register", "allow"},
+		{"operator", "device", "rate_limit", "allow"},
+		{"operator", "device", "disable", "allow"},
 		{"operator", "device", "watch", "allow"},
 	}
 
@@ -8,16 +8,27 @@ import (
 
 	"github.com/DangeL187/erax"
 	"github.com/joho/godotenv"
+
+	"auth/internal/shared/tlsutil"
 )
 
 type Config struct {
 	PostgresDSN           string
 	CasbinModelConfigPath string
+	JWTAudience           string
+	JWTIssuer             string
 
 	DBConnectTimeout      time.Duration
 	DeviceAccessTokenTTL  time.Duration
 	DeviceRefreshTokenTTL time.Duration
 	UserAccessTokenTTL    time.Duration
+
+	MQTTHookSecret      string
+	MQTTServicePassword string
+	MQTTServiceUsername string
+
+	GRPCTLS tlsutil.Config
+	HTTPTLS tlsutil.Config
 }
 
 func NewConfig() (*Config, error) {
@@ -30,13 +41,32 @@ func NewConfig() (*Config, error) {
 		return nil, erax.Wrap(err, "failed to load postgres dsn")
 	}
 
+	grpcTLS, err := tlsutil.FromEnv("GRPC")
+	if err != nil {
+		return nil, erax.Wrap(err, "failed to load gRPC tls config")
+	}
+
+	httpTLS, err := tlsutil.FromEnv("HTTP")
+	if err != nil {
+		return nil, erax.Wrap(err, "failed to load HTTP tls config")
+	}
+
 	cfg := &Config{
 		PostgresDSN:           postgresDSN,
 		CasbinModelConfigPath: "casbin_model.conf",
+		JWTAudience:           getEnv("JWT_AUDIENCE", "hivepulse"),
+		JWTIssuer:             getEnv("JWT_ISSUER", "http://localhost:8000"),
 		DBConnectTimeout:      1 * time.Minute,
 		DeviceAccessTokenTTL:  10 * time.Minute,
 		DeviceRefreshTokenTTL: 24 * time.Hour,
 		UserAccessTokenTTL:    10 * time.Minute,
+
+		MQTTHookSecret:      os.Getenv("MQTT_HOOK_SECRET"),      // empty disables the broker hooks
+		MQTTServicePassword: os.Getenv("MQTT_SERVICE_PASSWORD"), // empty disables the service login
+		MQTTServiceUsername: getEnv("MQTT_SERVICE_USERNAME", "ingress"),
+
+		GRPCTLS: grpcTLS,
+		HTTPTLS: httpTLS,
 	}
 
 	return cfg, nil
@@ -70,3 +100,11 @@ func loadPostgresDSN() (string, error) {
 
 	return dsn, nil
 }
+
+func getEnv(name, defaultValue string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+
+	return defaultValue
+}
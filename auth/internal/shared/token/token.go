@@ -4,11 +4,19 @@ import (
 	"time"
 )
 
+type DeviceClaims struct {
+	ID       uint
+	DeviceID string
+	Type     string
+}
+
 type Generator interface {
 	Generate(id uint, tokenType string, ttl time.Duration) (string, error)
 }
 
 type Manager interface {
 	Generate(id uint, tokenType string, ttl time.Duration) (string, error)
+	GenerateDevice(id uint, deviceID, tokenType string, ttl time.Duration) (string, error)
 	ParseToken(tokenString string) (uint, string, error)
+	ParseDeviceToken(tokenString string) (DeviceClaims, error)
 }
@@ -0,0 +1,48 @@
+import json
+
+import requests
+
+
+def broker_auth(url, client_id, username, password):
+    url = f"{url}/mqtt/auth"
+
+    headers = {
+        "Content-Type": "application/json",
+    }
+
+    data = {
+        "clientid": client_id,
+        "username": username,
+        "password": password,
+    }
+
+    try:
+        response = requests.post(url, headers=headers, json=data)
+        return json.loads(response.content.decode("utf-8"))
+    except Exception as e:
+        print('[ERROR]', e)
+
+    return None
+
+
+def broker_acl(url, username, topic, action):
+    url = f"{url}/mqtt/acl"
+
+    headers = {
+        "Content-Type": "application/json",
+    }
+
+    data = {
+        "clientid": username,
+        "username": username,
+        "topic": topic,
+        "action": action,
+    }
+
+    try:
+        response = requests.post(url, headers=headers, json=data)
+        return json.loads(response.content.decode("utf-8"))
+    except Exception as e:
+        print('[ERROR]', e)
+
+    return None
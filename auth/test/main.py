@@ -1,3 +1,4 @@
+from broker import broker_acl, broker_auth
 from get_roles import get_roles_admin, get_roles_user
 from grant_role import grant_role_admin_to_admin, grant_role_admin_to_user, grant_role_operator_to_user
 from login import login_admin, login_user
@@ -6,6 +7,9 @@ from refresh_device import refresh_device
 from register import register_user
 from register_device import register_device
 from revoke_role import revoke_role_admin_from_user, revoke_role_operator_from_user
+from set_disabled import set_disabled
+from set_rate_limit import set_rate_limit
+from well_known import get_jwks, get_openid_configuration
 
 # URL = "http://localhost:8000"  # local
 URL = "http://localhost:30080"  # k8s
@@ -55,7 +59,20 @@ def permissions_check_user_admin(admin_token, user_token):
     check("error" in res and res["error"] == 'user already has this role', 'grant role "admin" to user by user')
 
 
+def discovery_check():
+    print('\n[*] Discovery endpoints...')
+
+    res = get_jwks(URL)
+    check(res is not None and len(res["keys"]) > 0, 'get jwks')
+    check(all(key["kty"] == "OKP" and key["crv"] == "Ed25519" and key["kid"] for key in res["keys"]), 'jwks key format')
+
+    res = get_openid_configuration(URL)
+    check(res is not None and res["jwks_uri"].endswith("/.well-known/jwks.json"), 'get openid configuration')
+
+
 def main():
+    discovery_check()
+
     print('\n[*] Register and login...')
 
     register_user(URL)
@@ -94,6 +111,60 @@ def main():
     device_access_token = refresh_device(URL, device_refresh_token)
     check(device_access_token is not None, 'refresh device with refresh token')
 
+    print('\n[*] Broker authentication and authorization...')
+
+    res = broker_auth(URL, 'dev-1', 'dev-1', device_access_token)
+    check(res["result"] == 'allow' and not res["is_superuser"], 'broker auth device with access token')
+
+    res = broker_auth(URL, 'dev-1', 'dev-1', device_refresh_token)
+    check(res["result"] == 'deny', 'broker auth device with refresh token')
+
+    res = broker_auth(URL, 'dev-2', 'dev-2', device_access_token)
+    check(res["result"] == 'deny', 'broker auth device with token of another device')
+
+    res = broker_acl(URL, 'dev-1', 'devices/telemetry', 'publish')
+    check(res["result"] == 'allow', 'broker acl publish telemetry')
+
+    res = broker_acl(URL, 'dev-1', 'devices/dev-1/auth_response', 'subscribe')
+    check(res["result"] == 'allow', 'broker acl subscribe own topic')
+
+    res = broker_acl(URL, 'dev-1', 'devices/+/auth_response', 'subscribe')
+    check(res["result"] == 'deny', 'broker acl subscribe all devices')
+
+    res = broker_acl(URL, 'dev-1', 'devices/dev-2/throttle', 'subscribe')
+    check(res["result"] == 'deny', 'broker acl subscribe topic of another device')
+
+    print('\n[*] Device rate limits...')
+
+    res = set_rate_limit(URL, user_token, 'dev-1', 10, 20)
+    check("message" in res and res["message"] == 'rate limit has been set', 'set rate limit')
+
+    res = set_rate_limit(URL, user_token, 'dev-1', -1, 20)
+    check("error" in res and res["error"] == 'invalid input', 'set negative rate limit')
+
+    res = set_rate_limit(URL, user_token, 'dev-unknown', 10, 20)
+    check("error" in res and res["error"] == 'device not found', 'set rate limit of unknown device')
+
+    res = set_rate_limit(URL, user_token, 'dev-1')
+    check("message" in res and res["message"] == 'rate limit has been set', 'reset rate limit')
+
+    print('\n[*] Device disabling...')
+
+    res = set_disabled(URL, user_token, 'dev-1', True)
+    check("message" in res and res["message"] == 'device has been disabled', 'disable device')
+
+    access_token, _ = login_device(URL)
+    check(access_token is None, 'failed to login disabled device')
+
+    res = broker_auth(URL, 'dev-1', 'dev-1', device_access_token)
+    check(res["result"] == 'deny', 'broker auth disabled device')
+
+    res = set_disabled(URL, user_token, 'dev-unknown', True)
+    check("error" in res and res["error"] == 'device not found', 'disable unknown device')
+
+    res = set_disabled(URL, user_token, 'dev-1', False)
+    check("message" in res and res["message"] == 'device has been enabled', 'enable device')
+
     print('\n[*] Permissions revoking...')
 
     res = revoke_role_admin_from_user(URL, 2, user_token)
@@ -0,0 +1,27 @@
+import json
+
+import requests
+
+
+def set_disabled(url, token, device_id, disabled):
+    url = f"{url}/devices/{device_id}/disabled"
+
+    headers = {
+        "Content-Type": "application/json",
+    }
+
+    cookie = {
+        "access_token": token,
+    }
+
+    data = {
+        "disabled": disabled,
+    }
+
+    try:
+        response = requests.put(url, headers=headers, json=data, cookies=cookie)
+        return json.loads(response.content.decode("utf-8"))
+    except Exception as e:
+        print('[ERROR]', e)
+
+    return None
@@ -0,0 +1,28 @@
+import json
+
+import requests
+
+
+def set_rate_limit(url, token, device_id, rate_limit=None, rate_burst=None):
+    url = f"{url}/devices/{device_id}/rate_limit"
+
+    headers = {
+        "Content-Type": "application/json",
+    }
+
+    cookie = {
+        "access_token": token,
+    }
+
+    data = {
+        "rate_limit": rate_limit,
+        "rate_burst": rate_burst,
+    }
+
+    try:
+        response = requests.put(url, headers=headers, json=data, cookies=cookie)
+        return json.loads(response.content.decode("utf-8"))
+    except Exception as e:
+        print('[ERROR]', e)
+
+    return None
@@ -0,0 +1,21 @@
+import json
+
+import requests
+
+
+def get_jwks(url):
+    return _get(f"{url}/.well-known/jwks.json")
+
+
+def get_openid_configuration(url):
+    return _get(f"{url}/.well-known/openid-configuration")
+
+
+def _get(url):
+    try:
+        response = requests.get(url)
+        return json.loads(response.content.decode("utf-8"))
+    except Exception as e:
+        print('[ERROR]', e)
+
+    return None
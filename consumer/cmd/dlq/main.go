@@ -0,0 +1,91 @@
+// Command dlq inspects and re-drives messages that the ingress service moved to
+// the Kafka dead-letter topic.
+//
+//	go run ./cmd/dlq -topic device_telemetry.dlq inspect
+//	go run ./cmd/dlq -topic device_telemetry.dlq -limit 100 redrive
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go.uber.org/zap"
+	"os"
+	"time"
+
+	"consumer/internal/features/deadletter/domain"
+	"consumer/internal/features/deadletter/infra"
+	"consumer/internal/shared/tlsutil"
+)
+
+func main() {
+	logger, _ := zap.NewDevelopment()
+	zap.ReplaceGlobals(logger)
+	defer func() {
+		_ = logger.Sync()
+	}()
+
+	broker := flag.String("broker", getEnv("KAFKA_BROKER", "localhost:9092"), "Kafka broker address")
+	topic := flag.String("topic", getEnv("KAFKA_DLQ_TOPIC", ""), "dead-letter topic")
+	group := flag.String("group", "dlq-redrive", "consumer group used to track redrive progress")
+	target := flag.String("target", "", "redrive to this topic instead of each message's original topic")
+	limit := flag.Int("limit", 0, "maximum number of messages to handle, 0 for all")
+	flag.Usage = func() {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] inspect|redrive\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 || *topic == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// TLS is configured like the consumer service, through the KAFKA_TLS_* env vars
+	kafkaTLS, err := tlsutil.FromEnv("KAFKA")
+	if err != nil {
+		zap.S().Fatalf("invalid Kafka TLS config:\n%f", err)
+	}
+	tlsConfig, err := kafkaTLS.ClientTLS()
+	if err != nil {
+		zap.S().Fatalf("failed to load Kafka TLS config:\n%f", err)
+	}
+
+	deadLetters, err := infra.NewKafkaDeadLetters(*broker, tlsConfig, *topic)
+	if err != nil {
+		zap.S().Fatalf("failed to connect to Kafka:\n%f", err)
+	}
+	defer func() {
+		_ = deadLetters.Close()
+	}()
+
+	switch flag.Arg(0) {
+	case "inspect":
+		err = deadLetters.Inspect(*limit, printDeadLetter)
+	case "redrive":
+		var redriven int
+		redriven, err = deadLetters.Redrive(*group, *target, *limit)
+		zap.S().Infof("redrove %d messages", redriven)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		zap.S().Fatalf("failed to %s dead letters:\n%f", flag.Arg(0), err)
+	}
+}
+
+func printDeadLetter(dl *domain.DeadLetter) {
+	fmt.Printf("partition=%d offset=%d timestamp=%s\n", dl.Partition, dl.Offset, dl.Timestamp.Format(time.RFC3339))
+	for _, header := range dl.Headers {
+		fmt.Printf("  %s: %s\n", header.Key, header.Value)
+	}
+	fmt.Printf("  payload: %s\n\n", dl.Payload)
+}
+
+func getEnv(name, defaultValue string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+
+	return defaultValue
+}
@@ -16,7 +16,7 @@ import (
 )
 
 type App struct {
-	msgChan chan *sarama.ConsumerMessage
+	msgChans []chan *sarama.ConsumerMessage
 
 	cfg *config.Config
 
@@ -30,7 +30,7 @@ func (a *App) Run() {
 	ctx, cancel := context.WithCancel(context.Background())
 	a.cancel = cancel
 
-	a.messageBatchFlusher.Run(ctx, runtime.NumCPU()*2)
+	a.messageBatchFlusher.Run(ctx)
 
 	errChanIn := a.consumerLoop.Run(ctx)
 	go func() {
@@ -51,14 +51,25 @@ func (a *App) Stop() {
 		zap.S().Errorf("\n%f", err)
 	}
 
-	close(a.msgChan)
+	for _, msgChan := range a.msgChans {
+		close(msgChan)
+	}
 
 	a.messageBatchFlusher.Stop()
 }
 
 func NewApp() (*App, error) {
+	// one channel per flusher worker, 10,000 messages in total
+	workerCount := runtime.NumCPU() * 2
 	app := &App{
-		msgChan: make(chan *sarama.ConsumerMessage, 10000),
+		msgChans: make([]chan *sarama.ConsumerMessage, workerCount),
+	}
+	msgChansIn := make([]<-chan *sarama.ConsumerMessage, workerCount)
+	msgChansOut := make([]chan<- *sarama.ConsumerMessage, workerCount)
+	for i := range app.msgChans {
+		app.msgChans[i] = make(chan *sarama.ConsumerMessage, 10000/workerCount)
+		msgChansIn[i] = app.msgChans[i]
+		msgChansOut[i] = app.msgChans[i]
 	}
 
 	var err error
@@ -71,13 +82,17 @@ func NewApp() (*App, error) {
 	if err != nil {
 		return nil, erax.Wrap(err, "failed to initialize kafka-clickhouse flusher")
 	}
-	app.messageBatchFlusher = flusherRuntime.NewMessageBatchFlusher[sarama.ConsumerMessage](app.cfg, app.msgChan, flusher)
+	app.messageBatchFlusher = flusherRuntime.NewMessageBatchFlusher[sarama.ConsumerMessage](app.cfg, msgChansIn, flusher)
 
 	kafkaConsumer, err := consumerInfra.NewKafkaConsumer(app.cfg)
 	if err != nil {
 		return nil, erax.Wrap(err, "failed to create kafka consumer")
 	}
-	app.consumerLoop = consumerRuntime.NewConsumerLoop[sarama.ConsumerMessage](kafkaConsumer, app.msgChan)
+	// ingress keys messages by device ID, so sharding by partition keeps each device's messages in order
+	partitionKey := func(msg *sarama.ConsumerMessage) int {
+		return int(msg.Partition)
+	}
+	app.consumerLoop = consumerRuntime.NewConsumerLoop[sarama.ConsumerMessage](kafkaConsumer, msgChansOut, partitionKey)
 
 	return app, nil
 }
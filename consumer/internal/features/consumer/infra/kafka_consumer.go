@@ -44,6 +44,15 @@ func NewKafkaConsumer(cfg *config.Config) (*KafkaConsumer, error) {
 	kafkaConfig.Consumer.Offsets.AutoCommit.Enable = true
 	kafkaConfig.Consumer.Offsets.AutoCommit.Interval = 1 * time.Second
 
+	tlsConfig, err := cfg.KafkaTLS.ClientTLS()
+	if err != nil {
+		return nil, erax.Wrap(err, "failed to load kafka tls config")
+	}
+	if tlsConfig != nil {
+		kafkaConfig.Net.TLS.Enable = true
+		kafkaConfig.Net.TLS.Config = tlsConfig
+	}
+
 	cg, err := sarama.NewConsumerGroup([]string{cfg.KafkaBroker}, cfg.KafkaGroupID, kafkaConfig)
 	if err != nil {
 		return nil, erax.Wrap(err, "failed to create kafka consumer group")
@@ -15,9 +15,12 @@ type consumer[T any] interface {
 	Stop() error
 }
 
+// ConsumerLoop routes every message to one of msgChansOut by its shard key, so that messages sharing a key
+// (e.g. a Kafka partition, and therefore a device) are always handled by the same worker, in order.
 type ConsumerLoop[T any] struct {
-	consumer   consumer[T]
-	msgChanOut chan<- *T
+	consumer    consumer[T]
+	msgChansOut []chan<- *T
+	shardKey    func(msg *T) int
 }
 
 func (cl *ConsumerLoop[T]) Run(ctx context.Context) <-chan error {
@@ -52,15 +55,16 @@ func (cl *ConsumerLoop[T]) Stop() error {
 
 func (cl *ConsumerLoop[T]) handleIncomingMessage(msg *T) {
 	start := time.Now()
-	cl.msgChanOut <- msg
+	cl.msgChansOut[cl.shardKey(msg)%len(cl.msgChansOut)] <- msg
 	duration := time.Since(start).Seconds()
 	metrics.MessagesConsumed.Inc()
 	metrics.ConsumerLatency.Observe(duration)
 }
 
-func NewConsumerLoop[T any](consumer consumer[T], msgChanOut chan<- *T) *ConsumerLoop[T] {
+func NewConsumerLoop[T any](consumer consumer[T], msgChansOut []chan<- *T, shardKey func(msg *T) int) *ConsumerLoop[T] {
 	return &ConsumerLoop[T]{
-		consumer:   consumer,
-		msgChanOut: msgChanOut,
+		consumer:    consumer,
+		msgChansOut: msgChansOut,
+		shardKey:    shardKey,
 	}
 }
@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"time"
+)
+
+// Headers set by the ingress service on messages moved to the dead-letter topic.
+const (
+	HeaderOriginalTopic = "dlq_original_topic"
+	HeaderReason        = "dlq_reason"
+	HeaderAttempts      = "dlq_attempts"
+	HeaderFailedAt      = "dlq_failed_at"
+)
+
+type Header struct {
+	Key   string
+	Value string
+}
+
+type DeadLetter struct {
+	Partition int32
+	Offset    int64
+	Timestamp time.Time
+	Key       []byte
+	Headers   []Header
+	Payload   []byte
+}
+
+func (dl *DeadLetter) Header(key string) string {
+	for _, header := range dl.Headers {
+		if header.Key == key {
+			return header.Value
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,247 @@
+package infra
+
+import (
+	"crypto/tls"
+	"errors"
+	"strings"
+
+	"github.com/DangeL187/erax"
+	"github.com/IBM/sarama"
+
+	"consumer/internal/features/deadletter/domain"
+)
+
+// KafkaDeadLetters reads the dead-letter topic up to its high watermark at the
+// time of the call, so both operations terminate even while ingress keeps
+// appending failures.
+type KafkaDeadLetters struct {
+	client sarama.Client
+	topic  string
+}
+
+// Inspect passes up to limit dead letters (0 means all) to fn, oldest first
+// within each partition. No offsets are committed.
+func (kd *KafkaDeadLetters) Inspect(limit int, fn func(dl *domain.DeadLetter)) error {
+	consumer, err := sarama.NewConsumerFromClient(kd.client)
+	if err != nil {
+		return erax.Wrap(err, "failed to create kafka consumer")
+	}
+	defer func() {
+		_ = consumer.Close()
+	}()
+
+	partitions, err := kd.client.Partitions(kd.topic)
+	if err != nil {
+		return erax.Wrap(err, "failed to get dead-letter topic partitions")
+	}
+
+	seen := 0
+	for _, partition := range partitions {
+		oldest, err := kd.client.GetOffset(kd.topic, partition, sarama.OffsetOldest)
+		if err != nil {
+			return erax.Wrap(err, "failed to get oldest offset")
+		}
+
+		n, err := kd.readPartition(consumer, partition, oldest, limit-seen, func(dl *domain.DeadLetter) error {
+			fn(dl)
+			return nil
+		})
+		seen += n
+		if err != nil {
+			return err
+		}
+		if limit > 0 && seen >= limit {
+			break
+		}
+	}
+
+	return nil
+}
+
+// Redrive produces up to limit dead letters (0 means all) back to their
+// original topic, or to target when it is not empty, with the dlq_* headers
+// stripped. Progress is committed for groupID, so a later run resumes where
+// the previous one stopped instead of re-driving the same messages twice.
+func (kd *KafkaDeadLetters) Redrive(groupID, target string, limit int) (int, error) {
+	consumer, err := sarama.NewConsumerFromClient(kd.client)
+	if err != nil {
+		return 0, erax.Wrap(err, "failed to create kafka consumer")
+	}
+	defer func() {
+		_ = consumer.Close()
+	}()
+
+	producer, err := sarama.NewSyncProducerFromClient(kd.client)
+	if err != nil {
+		return 0, erax.Wrap(err, "failed to create kafka producer")
+	}
+	defer func() {
+		_ = producer.Close()
+	}()
+
+	offsetManager, err := sarama.NewOffsetManagerFromClient(groupID, kd.client)
+	if err != nil {
+		return 0, erax.Wrap(err, "failed to create kafka offset manager")
+	}
+	defer func() {
+		_ = offsetManager.Close()
+	}()
+
+	partitions, err := kd.client.Partitions(kd.topic)
+	if err != nil {
+		return 0, erax.Wrap(err, "failed to get dead-letter topic partitions")
+	}
+
+	var poms []sarama.PartitionOffsetManager
+	defer func() {
+		offsetManager.Commit()
+		for _, pom := range poms {
+			_ = pom.Close()
+		}
+	}()
+
+	redriven := 0
+	for _, partition := range partitions {
+		pom, err := offsetManager.ManagePartition(kd.topic, partition)
+		if err != nil {
+			return redriven, erax.Wrap(err, "failed to manage partition offsets")
+		}
+		poms = append(poms, pom)
+
+		next, _ := pom.NextOffset()
+		if next < 0 {
+			if next, err = kd.client.GetOffset(kd.topic, partition, sarama.OffsetOldest); err != nil {
+				return redriven, erax.Wrap(err, "failed to get oldest offset")
+			}
+		}
+
+		n, err := kd.readPartition(consumer, partition, next, limit-redriven, func(dl *domain.DeadLetter) error {
+			if err := kd.redrive(producer, dl, target); err != nil {
+				return err
+			}
+			pom.MarkOffset(dl.Offset+1, "")
+			return nil
+		})
+		redriven += n
+		if err != nil {
+			return redriven, err
+		}
+		if limit > 0 && redriven >= limit {
+			break
+		}
+	}
+
+	return redriven, nil
+}
+
+func (kd *KafkaDeadLetters) redrive(producer sarama.SyncProducer, dl *domain.DeadLetter, target string) error {
+	topic := target
+	if topic == "" {
+		topic = dl.Header(domain.HeaderOriginalTopic)
+	}
+	if topic == "" {
+		return errors.New("dead letter has no original topic, pass a target topic explicitly")
+	}
+
+	msg := &sarama.ProducerMessage{
+		Topic: topic,
+		Value: sarama.ByteEncoder(dl.Payload),
+	}
+	// Keep the key, so the message lands on its device's partition again. Unkeyed ones stay unkeyed.
+	if dl.Key != nil {
+		msg.Key = sarama.ByteEncoder(dl.Key)
+	}
+	for _, header := range dl.Headers {
+		if strings.HasPrefix(header.Key, "dlq_") {
+			continue
+		}
+		msg.Headers = append(msg.Headers, sarama.RecordHeader{Key: []byte(header.Key), Value: []byte(header.Value)})
+	}
+
+	if _, _, err := producer.SendMessage(msg); err != nil {
+		return erax.Wrap(err, "failed to redrive dead letter")
+	}
+
+	return nil
+}
+
+// readPartition calls fn for messages in [offset, high watermark) and returns
+// how many were handled. A non-positive limit means no limit.
+func (kd *KafkaDeadLetters) readPartition(consumer sarama.Consumer, partition int32, offset int64, limit int,
+	fn func(dl *domain.DeadLetter) error) (int, error) {
+	newest, err := kd.client.GetOffset(kd.topic, partition, sarama.OffsetNewest)
+	if err != nil {
+		return 0, erax.Wrap(err, "failed to get newest offset")
+	}
+	if offset >= newest {
+		return 0, nil
+	}
+
+	pc, err := consumer.ConsumePartition(kd.topic, partition, offset)
+	if err != nil {
+		return 0, erax.Wrap(err, "failed to consume dead-letter partition")
+	}
+	defer func() {
+		_ = pc.Close()
+	}()
+
+	handled := 0
+	for {
+		select {
+		case msg := <-pc.Messages():
+			dl := &domain.DeadLetter{
+				Partition: msg.Partition,
+				Offset:    msg.Offset,
+				Timestamp: msg.Timestamp,
+				Key:       msg.Key,
+				Payload:   msg.Value,
+			}
+			for _, header := range msg.Headers {
+				dl.Headers = append(dl.Headers, domain.Header{Key: string(header.Key), Value: string(header.Value)})
+			}
+
+			if err = fn(dl); err != nil {
+				return handled, err
+			}
+			handled++
+
+			if msg.Offset+1 >= newest || (limit > 0 && handled >= limit) {
+				return handled, nil
+			}
+		case consumerErr := <-pc.Errors():
+			return handled, erax.Wrap(consumerErr, "failed to read dead-letter partition")
+		}
+	}
+}
+
+func (kd *KafkaDeadLetters) Close() error {
+	err := kd.client.Close()
+	if err != nil {
+		return erax.Wrap(err, "failed to close kafka client")
+	}
+
+	return nil
+}
+
+// NewKafkaDeadLetters connects to kafkaBroker, over TLS unless tlsConfig is nil.
+func NewKafkaDeadLetters(kafkaBroker string, tlsConfig *tls.Config, topic string) (*KafkaDeadLetters, error) {
+	kafkaConfig := sarama.NewConfig()
+	kafkaConfig.Version = sarama.V4_0_0_0
+	kafkaConfig.Consumer.Return.Errors = true
+	kafkaConfig.Producer.RequiredAcks = sarama.WaitForAll
+	kafkaConfig.Producer.Return.Successes = true
+	if tlsConfig != nil {
+		kafkaConfig.Net.TLS.Enable = true
+		kafkaConfig.Net.TLS.Config = tlsConfig
+	}
+
+	client, err := sarama.NewClient([]string{kafkaBroker}, kafkaConfig)
+	if err != nil {
+		return nil, erax.Wrap(err, "failed to create kafka client")
+	}
+
+	return &KafkaDeadLetters{
+		client: client,
+		topic:  topic,
+	}, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"go.uber.org/zap"
+	"strconv"
 	"time"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
@@ -25,7 +26,67 @@ type deviceData struct {
 	Longitude float64 `json:"longitude"`
 	Altitude  float64 `json:"altitude"`
 	Battery   float64 `json:"battery"`
-	Timestamp int64   `json:"timestamp"`
+	Timestamp int64   `json:"timestamp"` // unix milliseconds since schema version 2, seconds before
+	Seq       uint64  `json:"seq"`
+
+	ReceivedAt  int64 `json:"received_at"` // unix milliseconds, ingress clock
+	ClockSkewed bool  `json:"clock_skewed"`
+
+	// Measurements holds JSON numbers, booleans and strings, stored in the matching typed Map column.
+	Measurements map[string]any `json:"measurements"`
+}
+
+func header(msg *sarama.ConsumerMessage, key string) string {
+	for _, h := range msg.Headers {
+		if string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+
+	return ""
+}
+
+// ingestedAt reads the ingested_at header (unix milliseconds) set by the ingress service, falling back to the
+// Kafka record timestamp for records produced without it.
+func ingestedAt(msg *sarama.ConsumerMessage) time.Time {
+	if ms, err := strconv.ParseInt(header(msg, "ingested_at"), 10, 64); err == nil {
+		return time.UnixMilli(ms)
+	}
+
+	return msg.Timestamp
+}
+
+// deviceTimestamp interprets the timestamp according to the record's schema_version header. Records written
+// before schema version 2 (including those without headers) carry unix seconds.
+func deviceTimestamp(msg *sarama.ConsumerMessage, timestamp int64) time.Time {
+	if version, err := strconv.Atoi(header(msg, "schema_version")); err == nil && version >= 2 {
+		return time.UnixMilli(timestamp)
+	}
+
+	return time.Unix(timestamp, 0)
+}
+
+// splitMeasurements sorts measurements into the measurements_number, measurements_bool and
+// measurements_string columns, so new sensors need no schema or code changes.
+func splitMeasurements(measurements map[string]any) (map[string]float64, map[string]bool, map[string]string) {
+	numbers := make(map[string]float64)
+	bools := make(map[string]bool)
+	strs := make(map[string]string)
+
+	for name, value := range measurements {
+		switch value := value.(type) {
+		case float64:
+			numbers[name] = value
+		case bool:
+			bools[name] = value
+		case string:
+			strs[name] = value
+		default:
+			zap.L().Debug("skipping measurement of unsupported type", zap.String("name", name))
+		}
+	}
+
+	return numbers, bools, strs
 }
 
 func (f *KafkaClickHouseFlusher) Flush(batch []*sarama.ConsumerMessage) {
@@ -38,7 +99,10 @@ func (f *KafkaClickHouseFlusher) Flush(batch []*sarama.ConsumerMessage) {
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	chBatch, err := f.conn.PrepareBatch(ctx, "INSERT INTO "+f.table+" (id, latitude, longitude, altitude, battery, timestamp)")
+	chBatch, err := f.conn.PrepareBatch(ctx, "INSERT INTO "+f.table+
+		" (id, latitude, longitude, altitude, battery, timestamp,"+
+		" measurements_number, measurements_bool, measurements_string,"+
+		" ingested_at, received_at, clock_skewed, seq)")
 	if err != nil {
 		zap.L().Error("ClickHouse PrepareBatch failed", zap.Error(err))
 		metrics.FlushErrors.Inc()
@@ -53,7 +117,8 @@ func (f *KafkaClickHouseFlusher) Flush(batch []*sarama.ConsumerMessage) {
 			continue
 		}
 
-		ts := time.Unix(device.Timestamp, 0)
+		ts := deviceTimestamp(msg, device.Timestamp)
+		numbers, bools, strs := splitMeasurements(device.Measurements)
 
 		if err = chBatch.Append(
 			device.ID,
@@ -62,6 +127,13 @@ func (f *KafkaClickHouseFlusher) Flush(batch []*sarama.ConsumerMessage) {
 			device.Altitude,
 			device.Battery,
 			ts,
+			numbers,
+			bools,
+			strs,
+			ingestedAt(msg),
+			time.UnixMilli(device.ReceivedAt),
+			device.ClockSkewed,
+			device.Seq,
 		); err != nil {
 			zap.L().Error("ClickHouse batch append failed", zap.Error(err))
 			metrics.FlushErrors.Inc()
@@ -78,6 +150,11 @@ func (f *KafkaClickHouseFlusher) Flush(batch []*sarama.ConsumerMessage) {
 }
 
 func NewKafkaClickHouseFlusher(cfg *config.Config) (*KafkaClickHouseFlusher, error) {
+	tlsConfig, err := cfg.ClickHouseTLS.ClientTLS()
+	if err != nil {
+		return nil, erax.Wrap(err, "failed to load clickhouse tls config")
+	}
+
 	conn, err := clickhouse.Open(&clickhouse.Options{
 		Addr: []string{cfg.ClickHouseDSN},
 		Auth: clickhouse.Auth{
@@ -90,6 +167,7 @@ func NewKafkaClickHouseFlusher(cfg *config.Config) (*KafkaClickHouseFlusher, err
 		DialTimeout:  time.Second * 5,
 		ReadTimeout:  time.Second * 10,
 		Debug:        false,
+		TLS:          tlsConfig, // nil for plaintext
 	})
 	if err != nil {
 		return nil, erax.Wrap(err, "failed to open clickhouse")
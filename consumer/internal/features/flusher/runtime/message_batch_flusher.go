@@ -9,16 +9,18 @@ import (
 	"consumer/internal/shared/config"
 )
 
+// MessageBatchFlusher runs one worker per input channel, so the order of messages within a channel is
+// preserved across flushes.
 type MessageBatchFlusher[T any] struct {
-	cfg       *config.Config
-	flusher   domain.Flusher[T]
-	msgChanIn <-chan *T
-	wg        sync.WaitGroup
+	cfg        *config.Config
+	flusher    domain.Flusher[T]
+	msgChansIn []<-chan *T
+	wg         sync.WaitGroup
 }
 
-func (mbf *MessageBatchFlusher[T]) Run(ctx context.Context, workerCount int) {
-	mbf.wg.Add(workerCount)
-	for i := 0; i < workerCount; i++ {
+func (mbf *MessageBatchFlusher[T]) Run(ctx context.Context) {
+	mbf.wg.Add(len(mbf.msgChansIn))
+	for _, msgChanIn := range mbf.msgChansIn {
 		go func() {
 			defer mbf.wg.Done()
 
@@ -29,7 +31,7 @@ func (mbf *MessageBatchFlusher[T]) Run(ctx context.Context, workerCount int) {
 
 			for {
 				select {
-				case msg, ok := <-mbf.msgChanIn:
+				case msg, ok := <-msgChanIn:
 					if !ok {
 						mbf.flusher.Flush(batch)
 						return
@@ -57,10 +59,11 @@ func (mbf *MessageBatchFlusher[T]) Stop() {
 	mbf.wg.Wait()
 }
 
-func NewMessageBatchFlusher[T any](cfg *config.Config, msgChanIn <-chan *T, flusher domain.Flusher[T]) *MessageBatchFlusher[T] {
+func NewMessageBatchFlusher[T any](cfg *config.Config, msgChansIn []<-chan *T,
+	flusher domain.Flusher[T]) *MessageBatchFlusher[T] {
 	return &MessageBatchFlusher[T]{
-		cfg:       cfg,
-		flusher:   flusher,
-		msgChanIn: msgChanIn,
+		cfg:        cfg,
+		flusher:    flusher,
+		msgChansIn: msgChansIn,
 	}
 }
@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/joho/godotenv"
+
+	"consumer/internal/shared/tlsutil"
 )
 
 type Config struct {
@@ -22,6 +24,9 @@ type Config struct {
 
 	BatchInterval time.Duration
 	BatchSize     int
+
+	ClickHouseTLS tlsutil.Config
+	KafkaTLS      tlsutil.Config
 }
 
 func NewConfig() (*Config, error) {
@@ -51,6 +56,16 @@ func NewConfig() (*Config, error) {
 		return nil, fmt.Errorf("invalid BATCH_SIZE: %s", vars["BATCH_SIZE"])
 	}
 
+	clickHouseTLS, err := tlsutil.FromEnv("CLICKHOUSE")
+	if err != nil {
+		return nil, err
+	}
+
+	kafkaTLS, err := tlsutil.FromEnv("KAFKA")
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
 		BatchInterval:      time.Second,
 		BatchSize:          batchSize,
@@ -62,5 +77,8 @@ func NewConfig() (*Config, error) {
 		KafkaBroker:        vars["KAFKA_BROKER"],
 		KafkaGroupID:       vars["KAFKA_GROUP_ID"],
 		KafkaTopic:         vars["KAFKA_TOPIC"],
+
+		ClickHouseTLS: clickHouseTLS,
+		KafkaTLS:      kafkaTLS,
 	}, nil
 }
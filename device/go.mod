@@ -4,6 +4,7 @@ go 1.24.3
 
 require (
 	github.com/DangeL187/erax v0.2.3
+	github.com/eclipse/paho.golang v0.22.0
 	github.com/eclipse/paho.mqtt.golang v1.5.0
 	go.uber.org/zap v1.27.0
 )
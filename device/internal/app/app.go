@@ -2,6 +2,7 @@ package app
 
 import (
 	"context"
+	"go.uber.org/zap"
 	"strconv"
 
 	"device/internal/http"
@@ -37,12 +38,17 @@ func NewApp(id int) *App {
 	cfg := config.NewConfig()
 	cfg.DeviceID = "dev-" + strconv.Itoa(id)
 
+	tlsConfig, err := cfg.TLS.ClientTLS()
+	if err != nil {
+		zap.S().Fatalf("failed to load TLS config:\n%f", err)
+	}
+
 	t := &tokens.Tokens{}
-	authService := http.NewAuthService(cfg, t)
+	authService := http.NewAuthService(cfg, t, tlsConfig)
 
 	return &App{
 		config:         cfg,
 		tokens:         t,
-		metricsService: mqtt.NewMetricsService(cfg, t, authService),
+		metricsService: mqtt.NewMetricsService(cfg, t, authService, tlsConfig),
 	}
 }
@@ -3,6 +3,7 @@ package http
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"go.uber.org/zap"
@@ -203,8 +204,11 @@ func (as *AuthService) refresh(url, refreshToken string) (string, error) {
 	return accessToken, nil
 }
 
-func NewAuthService(cfg *config.Config, tokens *tokens.Tokens) *AuthService {
+func NewAuthService(cfg *config.Config, tokens *tokens.Tokens, tlsConfig *tls.Config) *AuthService {
 	client := &http.Client{Timeout: time.Minute}
+	if tlsConfig != nil {
+		client.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	}
 
 	return &AuthService{
 		auth:   make(chan struct{}),
@@ -0,0 +1,64 @@
+package mqtt
+
+import (
+	"context"
+
+	"github.com/DangeL187/erax"
+	"github.com/eclipse/paho.mqtt.golang"
+)
+
+// client is the part of an MQTT connection the MetricsService uses, so that v3 and v5 connections are
+// interchangeable.
+type client interface {
+	Connect(ctx context.Context) error
+	Subscribe(topic string, handler func(payload []byte)) error
+	// Publish sends payload with correlationData, which only MQTT v5 carries; responses echo it back.
+	Publish(topic string, payload []byte, correlationData []byte) error
+	Disconnect()
+}
+
+type v3Client struct {
+	mqttClient mqtt.Client
+	qos        byte
+}
+
+func (c *v3Client) Connect(_ context.Context) error {
+	if token := c.mqttClient.Connect(); token.Wait() && token.Error() != nil {
+		return erax.Wrap(token.Error(), "failed to connect to MQTT broker")
+	}
+
+	return nil
+}
+
+func (c *v3Client) Subscribe(topic string, handler func(payload []byte)) error {
+	token := c.mqttClient.Subscribe(topic, 0, func(_ mqtt.Client, msg mqtt.Message) {
+		handler(msg.Payload())
+	})
+	if token.Wait() && token.Error() != nil {
+		return erax.Wrap(token.Error(), "failed to subscribe to "+topic)
+	}
+
+	return nil
+}
+
+func (c *v3Client) Publish(topic string, payload []byte, _ []byte) error {
+	token := c.mqttClient.Publish(topic, c.qos, false, payload)
+	if token.Wait() && token.Error() != nil {
+		return erax.Wrap(token.Error(), "failed to publish to "+topic)
+	}
+
+	return nil
+}
+
+func (c *v3Client) Disconnect() {
+	if c.mqttClient.IsConnected() {
+		c.mqttClient.Disconnect(250)
+	}
+}
+
+func newV3Client(opts *mqtt.ClientOptions, qos byte) *v3Client {
+	return &v3Client{
+		mqttClient: mqtt.NewClient(opts),
+		qos:        qos,
+	}
+}
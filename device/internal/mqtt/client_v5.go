@@ -0,0 +1,176 @@
+package mqtt
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"go.uber.org/zap"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/DangeL187/erax"
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
+
+	"device/internal/shared/config"
+	"device/internal/shared/tokens"
+)
+
+// v5Client publishes telemetry with an MQTT v5 content type and asks for auth errors on responseTopic, tagged with
+// the correlation data of the message that caused them.
+type v5Client struct {
+	cfg           *config.Config
+	tlsConfig     *tls.Config
+	tokens        *tokens.Tokens
+	responseTopic string
+
+	cm *autopaho.ConnectionManager
+
+	handlersMu sync.RWMutex
+	handlers   map[string]func(payload []byte)
+}
+
+func (c *v5Client) Connect(ctx context.Context) error {
+	brokerURL, err := url.Parse(c.cfg.MqttBrokerURL)
+	if err != nil {
+		return erax.Wrap(err, "failed to parse MQTT broker URL")
+	}
+
+	c.cm, err = autopaho.NewConnection(ctx, autopaho.ClientConfig{
+		ServerUrls:                    []*url.URL{brokerURL},
+		TlsCfg:                        c.tlsConfig,
+		KeepAlive:                     30,
+		CleanStartOnInitialConnection: c.cfg.MqttSessionExpiry == 0,
+		SessionExpiryInterval:         uint32(c.cfg.MqttSessionExpiry.Seconds()),
+		ConnectRetryDelay:             c.cfg.ConnectRetryInterval,
+		ConnectPacketBuilder:          c.withCredentials,
+		OnConnectionUp:                c.resubscribe,
+		OnConnectError: func(err error) {
+			zap.L().Warn("failed to connect to MQTT broker", zap.Error(err))
+		},
+		ClientConfig: paho.ClientConfig{
+			ClientID:          c.cfg.DeviceID,
+			OnPublishReceived: []func(paho.PublishReceived) (bool, error){c.route},
+			OnServerDisconnect: func(d *paho.Disconnect) {
+				zap.L().Warn("disconnected by MQTT broker", zap.Uint8("reason code", d.ReasonCode))
+			},
+		},
+	})
+	if err != nil {
+		return erax.Wrap(err, "failed to create MQTT connection")
+	}
+
+	if err = c.cm.AwaitConnection(ctx); err != nil {
+		return erax.Wrap(err, "failed to connect to MQTT broker")
+	}
+
+	return nil
+}
+
+func (c *v5Client) Subscribe(topic string, handler func(payload []byte)) error {
+	c.handlersMu.Lock()
+	c.handlers[topic] = handler
+	c.handlersMu.Unlock()
+
+	return c.subscribe(c.cm, topic)
+}
+
+func (c *v5Client) Publish(topic string, payload []byte, correlationData []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := c.cm.Publish(ctx, &paho.Publish{
+		Topic:   topic,
+		QoS:     c.cfg.MqttQoS,
+		Payload: payload,
+		Properties: &paho.PublishProperties{
+			ContentType:     "application/json",
+			ResponseTopic:   c.responseTopic,
+			CorrelationData: correlationData,
+		},
+	})
+	if err != nil {
+		return erax.Wrap(err, "failed to publish to "+topic)
+	}
+
+	return nil
+}
+
+func (c *v5Client) Disconnect() {
+	if c.cm == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+
+	_ = c.cm.Disconnect(ctx)
+}
+
+func (c *v5Client) subscribe(cm *autopaho.ConnectionManager, topic string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	suback, err := cm.Subscribe(ctx, &paho.Subscribe{
+		Subscriptions: []paho.SubscribeOptions{{Topic: topic, QoS: 0}},
+	})
+	if err != nil {
+		return erax.Wrap(err, "failed to subscribe to "+topic)
+	}
+	for _, reason := range suback.Reasons {
+		if reason >= 0x80 {
+			return fmt.Errorf("subscription to %s refused with reason code 0x%02x", topic, reason)
+		}
+	}
+
+	return nil
+}
+
+// withCredentials logs in with the current access token on every (re)connection, as the broker checks it at CONNECT.
+func (c *v5Client) withCredentials(connect *paho.Connect, _ *url.URL) (*paho.Connect, error) {
+	connect.Username = c.cfg.DeviceID
+	connect.UsernameFlag = true
+	connect.Password = []byte(c.tokens.GetAccess())
+	connect.PasswordFlag = true
+
+	return connect, nil
+}
+
+// resubscribe restores the subscriptions after a reconnection that did not resume the session.
+func (c *v5Client) resubscribe(cm *autopaho.ConnectionManager, connAck *paho.Connack) {
+	if connAck.SessionPresent {
+		return
+	}
+
+	c.handlersMu.RLock()
+	defer c.handlersMu.RUnlock()
+
+	for topic := range c.handlers {
+		if err := c.subscribe(cm, topic); err != nil {
+			zap.L().Error("failed to resubscribe", zap.Error(err))
+		}
+	}
+}
+
+func (c *v5Client) route(pr paho.PublishReceived) (bool, error) {
+	c.handlersMu.RLock()
+	handler, ok := c.handlers[pr.Packet.Topic]
+	c.handlersMu.RUnlock()
+
+	if ok {
+		handler(pr.Packet.Payload)
+	}
+
+	return ok, nil
+}
+
+func newV5Client(cfg *config.Config, tokens *tokens.Tokens, tlsConfig *tls.Config) *v5Client {
+	return &v5Client{
+		cfg:           cfg,
+		tlsConfig:     tlsConfig,
+		tokens:        tokens,
+		responseTopic: "devices/" + cfg.DeviceID + "/auth_response",
+		handlers:      make(map[string]func(payload []byte)),
+	}
+}
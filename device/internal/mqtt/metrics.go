@@ -2,8 +2,11 @@ package mqtt
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"go.uber.org/zap"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/eclipse/paho.mqtt.golang"
@@ -22,52 +25,67 @@ type deviceData struct {
 	Battery   float64 `json:"battery"`
 	Timestamp int64   `json:"timestamp"`
 	Token     string  `json:"token"`
+	Seq       uint64  `json:"seq"`
+
+	// Measurements carries any additional sensor readings: numbers, booleans or strings.
+	Measurements map[string]any `json:"measurements,omitempty"`
 }
 
 type MetricsService struct {
 	authService *http.AuthService
 	cfg         *config.Config
 	tokens      *tokens.Tokens
-	mqttClient  mqtt.Client
+	mqttClient  client
 
 	dataChan chan deviceData
+	seq      uint64
+
+	throttledUntil atomic.Int64 // unix milliseconds
 }
 
 func (ms *MetricsService) Run(ctx context.Context) {
 	zap.L().Info("MetricsService started")
 
-	if token := ms.mqttClient.Connect(); token.Wait() && token.Error() != nil {
-		zap.L().Error("failed to connect to MQTT broker", zap.Error(token.Error()))
-		return
-	}
-
+	// the broker authenticates the device by its access token, so it has to log in before connecting
 	if !ms.authService.Login(ctx) {
 		return
 	}
 
 	m.AuthCounter.Add(1)
 
-	token := ms.mqttClient.Subscribe(
+	if err := ms.mqttClient.Connect(ctx); err != nil {
+		zap.L().Error("failed to connect to MQTT broker", zap.Error(err))
+		return
+	}
+
+	err := ms.mqttClient.Subscribe(
 		"devices/"+ms.cfg.DeviceID+"/auth_response",
-		0,
-		func(_ mqtt.Client, msg mqtt.Message) {
-			ms.handleAuthResponse(ctx, msg)
+		func(payload []byte) {
+			ms.handleAuthResponse(ctx, payload)
 		},
 	)
-	if token.Wait() && token.Error() != nil {
-		zap.L().Error("failed to subscribe to auth response topic", zap.Error(token.Error()))
+	if err != nil {
+		zap.L().Error("failed to subscribe to auth response topic", zap.Error(err))
+		return
+	}
+
+	err = ms.mqttClient.Subscribe(
+		"devices/"+ms.cfg.DeviceID+"/throttle",
+		ms.handleThrottle,
+	)
+	if err != nil {
+		zap.L().Error("failed to subscribe to throttle topic", zap.Error(err))
 		return
 	}
 
 	ms.startPublishing(ctx)
 	ms.startMetricsPutting(ctx)
+	ms.startTokenRefreshing(ctx)
 }
 
 func (ms *MetricsService) Stop() {
 	zap.L().Info("MetricsService stopping...")
-	if ms.mqttClient.IsConnected() {
-		ms.mqttClient.Disconnect(250)
-	}
+	ms.mqttClient.Disconnect()
 	zap.L().Info("MetricsService stopped")
 }
 
@@ -77,6 +95,9 @@ func (ms *MetricsService) startPublishing(ctx context.Context) {
 			if !ms.authService.WaitForAuth(ctx) {
 				return
 			}
+			if !ms.waitForThrottle(ctx) {
+				return
+			}
 
 			select {
 			case <-ctx.Done():
@@ -104,6 +125,49 @@ func (ms *MetricsService) startMetricsPutting(ctx context.Context) {
 	}()
 }
 
+// startTokenRefreshing refreshes the access token TokenRefreshMargin before it expires, as the broker checks it on
+// every (re)connection and ingress on every message.
+func (ms *MetricsService) startTokenRefreshing(ctx context.Context) {
+	go func() {
+		for {
+			expiresAt := ms.tokens.AccessExpiresAt()
+			if expiresAt.IsZero() {
+				zap.L().Warn("access token has no expiry, not refreshing it proactively")
+				return
+			}
+
+			wait := time.Until(expiresAt.Add(-ms.cfg.TokenRefreshMargin))
+			if !sleep(ctx, wait) {
+				return
+			}
+
+			ms.authService.Refresh(ctx)
+
+			// Refresh gives up on errors other than network ones, so do not spin on the same token
+			if ms.tokens.AccessExpiresAt().Equal(expiresAt) && !sleep(ctx, ms.cfg.ConnectRetryInterval) {
+				return
+			}
+		}
+	}()
+}
+
+// sleep waits for d, returning false if ctx is cancelled meanwhile.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
 func (ms *MetricsService) putInQueue() {
 	// [WARNING] Simulated data:
 	msg := deviceData{
@@ -112,11 +176,23 @@ func (ms *MetricsService) putInQueue() {
 		Longitude: 37.6,
 		Altitude:  120,
 		Battery:   88.0,
-		Timestamp: time.Now().Unix(),
+		Timestamp: time.Now().UnixMilli(),
+		Seq:       ms.nextSeq(),
+		Measurements: map[string]any{
+			"temperature": 21.5,
+			"speed":       12.3,
+			"heading":     270.0,
+		},
 	}
 	ms.dataChan <- msg
 }
 
+// nextSeq numbers readings in the order they are taken, starting at 1, so ingress can detect duplicates and gaps.
+func (ms *MetricsService) nextSeq() uint64 {
+	ms.seq++
+	return ms.seq
+}
+
 func (ms *MetricsService) publish(msg deviceData) {
 	msg.Token = ms.tokens.GetAccess()
 
@@ -126,44 +202,111 @@ func (ms *MetricsService) publish(msg deviceData) {
 		return
 	}
 
-	token := ms.mqttClient.Publish("devices/telemetry", 0, false, payload)
-	if token.Wait() && token.Error() != nil {
-		zap.L().Error("failed to publish device data payload", zap.Error(token.Error()))
+	// the sequence number identifies the message in auth errors sent back over MQTT v5
+	err = ms.mqttClient.Publish("devices/telemetry", payload, []byte(strconv.FormatUint(msg.Seq, 10)))
+	if err != nil {
+		zap.L().Error("failed to publish device data payload", zap.Error(err))
 		return
 	}
 
 	m.Counter.Add(1)
 }
 
-type authResponse struct {
-	Error string `json:"error"`
+// Error codes of auth responses and throttle notices sent by ingress.
+const (
+	codeTokenExpired     = "token_expired"
+	codeSignatureInvalid = "signature_invalid"
+	codeDeviceDisabled   = "device_disabled"
+	codeIdentityMismatch = "identity_mismatch"
+	codeRateLimited      = "rate_limited"
+	codeUnavailable      = "unavailable"
+)
+
+type errorResponse struct {
+	Code          string `json:"code"`
+	Error         string `json:"error"`
+	RetryAfterMs  int64  `json:"retry_after_ms"`
+	CorrelationID string `json:"correlation_id"` // seq of the rejected message
 }
 
-func (ms *MetricsService) handleAuthResponse(ctx context.Context, msg mqtt.Message) {
-	var resp authResponse
-	if err := json.Unmarshal(msg.Payload(), &resp); err != nil {
+func (ms *MetricsService) handleAuthResponse(ctx context.Context, payload []byte) {
+	var resp errorResponse
+	if err := json.Unmarshal(payload, &resp); err != nil {
 		zap.L().Error("failed to unmarshal message payload", zap.Error(err))
 		return
 	}
 
-	if resp.Error != "" {
-		zap.L().Debug("Auth failed", zap.String("Error", resp.Error))
+	if resp.Error == "" && resp.Code == "" {
+		zap.L().Info("Auth successful")
+		return
+	}
+
+	zap.L().Debug("Auth failed", zap.String("Code", resp.Code), zap.String("Error", resp.Error),
+		zap.String("Seq", resp.CorrelationID))
+
+	switch resp.Code {
+	case codeTokenExpired:
+		ms.authService.ResetAuth()
+		ms.authService.Refresh(ctx)
+	case codeSignatureInvalid:
+		// the refresh token is signed the same way, so only a new login helps
+		ms.authService.ResetAuth()
+		ms.authService.Login(ctx)
+	case codeDeviceDisabled:
+		// stays unauthenticated, which pauses publishing until the simulator is restarted
+		zap.L().Error("Device is disabled, stopping publishing")
+		ms.authService.ResetAuth()
+	case codeIdentityMismatch:
+		// the token is fine but was sent under another device ID, which new tokens would not change
+		zap.L().Error("Access token was used under another device ID", zap.String("Seq", resp.CorrelationID))
+	case codeRateLimited, codeUnavailable:
+		// the token was not rejected, so it is kept for when ingress can check it again
+		ms.throttle(resp.RetryAfterMs)
+	default:
+		// ingress without error codes
 		ms.authService.ResetAuth()
 		ms.authService.Refresh(ctx)
+	}
+}
+
+func (ms *MetricsService) handleThrottle(payload []byte) {
+	var notice errorResponse
+	if err := json.Unmarshal(payload, &notice); err != nil {
+		zap.L().Error("failed to unmarshal message payload", zap.Error(err))
 		return
 	}
 
-	zap.L().Info("Auth successful")
+	ms.throttle(notice.RetryAfterMs)
+}
+
+func (ms *MetricsService) throttle(retryAfterMs int64) {
+	zap.L().Debug("Throttled", zap.Int64("retry after ms", retryAfterMs))
+	ms.throttledUntil.Store(time.Now().UnixMilli() + retryAfterMs)
 }
 
-func NewMetricsService(cfg *config.Config, tokens *tokens.Tokens, authService *http.AuthService) *MetricsService {
-	opts := mqtt.NewClientOptions().
-		AddBroker(cfg.MqttBrokerURL).
-		SetClientID(cfg.DeviceID).
-		SetAutoReconnect(true).
-		SetConnectRetryInterval(cfg.ConnectRetryInterval).
-		SetMaxReconnectInterval(cfg.MaxReconnectInterval)
-	mqttClient := mqtt.NewClient(opts)
+// waitForThrottle pauses publishing until the retry time from the last throttle notice, returning false if ctx
+// is cancelled meanwhile.
+func (ms *MetricsService) waitForThrottle(ctx context.Context) bool {
+	return sleep(ctx, time.Until(time.UnixMilli(ms.throttledUntil.Load())))
+}
+
+func NewMetricsService(cfg *config.Config, tokens *tokens.Tokens, authService *http.AuthService,
+	tlsConfig *tls.Config) *MetricsService {
+	var mqttClient client
+	if cfg.MqttVersion == 5 {
+		mqttClient = newV5Client(cfg, tokens, tlsConfig)
+	} else {
+		mqttClient = newV3Client(mqtt.NewClientOptions().
+			AddBroker(cfg.MqttBrokerURL).
+			SetClientID(cfg.DeviceID).
+			SetCredentialsProvider(func() (string, string) {
+				return cfg.DeviceID, tokens.GetAccess()
+			}).
+			SetTLSConfig(tlsConfig).
+			SetAutoReconnect(true).
+			SetConnectRetryInterval(cfg.ConnectRetryInterval).
+			SetMaxReconnectInterval(cfg.MaxReconnectInterval), cfg.MqttQoS)
+	}
 
 	ms := &MetricsService{
 		authService: authService,
@@ -1,27 +1,49 @@
 package config
 
-import "time"
+import (
+	"crypto/tls"
+	"time"
+
+	"device/internal/shared/tlsutil"
+)
 
 type Config struct {
 	AuthServerURL string
 	MqttBrokerURL string
+	MqttVersion   int // 3 or 5
+
+	// MqttSessionExpiry keeps the MQTT v5 session (subscriptions, queued messages) across reconnects
+	MqttSessionExpiry time.Duration
+	// MqttQoS of telemetry; 1 lets ingress's ack overflow policy have refused messages redelivered
+	MqttQoS byte
 
 	DeviceID       string
 	DevicePassword string
 
+	// TLS for the MQTT broker and the auth server; use ssl:// (or mqtts://) and https:// URLs with it
+	TLS tlsutil.Config
+
 	ConnectRetryInterval   time.Duration
 	MaxReconnectInterval   time.Duration
 	PublishMetricsInterval time.Duration
+
+	// TokenRefreshMargin is how long before the access token expires it is refreshed
+	TokenRefreshMargin time.Duration
 }
 
 func NewConfig() *Config {
 	return &Config{
 		AuthServerURL:          "http://localhost:30080", // 8000 - local, 30080 - k8s
 		MqttBrokerURL:          "tcp://localhost:31883",  // 1883 - local, 31883 - k8s
+		MqttVersion:            3,
+		MqttSessionExpiry:      time.Minute,
+		MqttQoS:                1,
 		DeviceID:               "",
 		DevicePassword:         "secret",
+		TLS:                    tlsutil.Config{MinVersion: tls.VersionTLS12},
 		ConnectRetryInterval:   5 * time.Second,
 		MaxReconnectInterval:   30 * time.Second,
 		PublishMetricsInterval: time.Millisecond * 10,
+		TokenRefreshMargin:     time.Minute,
 	}
 }
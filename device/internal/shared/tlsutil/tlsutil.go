@@ -0,0 +1,147 @@
+// Package tlsutil builds the TLS configs of every network hop. The auth, ingress, consumer and device modules are
+// built on their own, so each keeps an identical copy of this file; change them together.
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Config describes TLS for one network hop. It is read from <PREFIX>_TLS_* env vars:
+//
+//	<PREFIX>_TLS_ENABLED      "true" to enable TLS
+//	<PREFIX>_TLS_CA           PEM bundle verifying the peer (system roots when empty); on servers, requires client
+//	                          certificates signed by it (mTLS)
+//	<PREFIX>_TLS_CERT         PEM certificate: the server's own, or the client's for mTLS
+//	<PREFIX>_TLS_KEY          PEM private key of <PREFIX>_TLS_CERT
+//	<PREFIX>_TLS_SERVER_NAME  name expected in the server certificate, when it differs from the dialled host
+//	<PREFIX>_TLS_MIN_VERSION  "1.2" (default) or "1.3"
+type Config struct {
+	Enabled    bool
+	CAFile     string
+	CertFile   string
+	KeyFile    string
+	ServerName string
+	MinVersion uint16
+}
+
+func FromEnv(prefix string) (Config, error) {
+	prefix += "_TLS"
+
+	enabled, err := strconv.ParseBool(getEnv(prefix+"_ENABLED", "false"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid %s_ENABLED: %s", prefix, os.Getenv(prefix+"_ENABLED"))
+	}
+
+	var minVersion uint16
+	switch v := getEnv(prefix+"_MIN_VERSION", "1.2"); v {
+	case "1.2":
+		minVersion = tls.VersionTLS12
+	case "1.3":
+		minVersion = tls.VersionTLS13
+	default:
+		return Config{}, fmt.Errorf("invalid %s_MIN_VERSION: %s", prefix, v)
+	}
+
+	cfg := Config{
+		Enabled:    enabled,
+		CAFile:     os.Getenv(prefix + "_CA"),
+		CertFile:   os.Getenv(prefix + "_CERT"),
+		KeyFile:    os.Getenv(prefix + "_KEY"),
+		ServerName: os.Getenv(prefix + "_SERVER_NAME"),
+		MinVersion: minVersion,
+	}
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return Config{}, fmt.Errorf("%s_CERT and %s_KEY must be set together", prefix, prefix)
+	}
+
+	return cfg, nil
+}
+
+// ClientTLS returns the tls.Config for dialling the hop, or nil when TLS is disabled.
+func (c Config) ClientTLS() (*tls.Config, error) {
+	if !c.Enabled {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: c.MinVersion,
+		ServerName: c.ServerName,
+	}
+
+	if c.CAFile != "" {
+		pool, err := loadCertPool(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// ServerTLS returns the tls.Config for serving the hop, or nil when TLS is disabled. With a CA bundle, clients must
+// present a certificate signed by it (mTLS).
+func (c Config) ServerTLS() (*tls.Config, error) {
+	if !c.Enabled {
+		return nil, nil
+	}
+	if c.CertFile == "" {
+		return nil, errors.New("a server certificate and key are required for TLS")
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:   c.MinVersion,
+		Certificates: []tls.Certificate{cert},
+	}
+
+	if c.CAFile != "" {
+		pool, err := loadCertPool(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConfig, nil
+}
+
+func loadCertPool(caFile string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no certificates found in CA bundle " + caFile)
+	}
+
+	return pool, nil
+}
+
+func getEnv(name, defaultValue string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+
+	return defaultValue
+}
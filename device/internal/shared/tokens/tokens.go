@@ -1,11 +1,18 @@
 package tokens
 
-import "sync"
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
 
 type Tokens struct {
-	mu           sync.RWMutex
-	accessToken  string
-	refreshToken string
+	mu              sync.RWMutex
+	accessToken     string
+	accessExpiresAt time.Time
+	refreshToken    string
 }
 
 func (t *Tokens) GetAccess() string {
@@ -14,6 +21,13 @@ func (t *Tokens) GetAccess() string {
 	return t.accessToken
 }
 
+// AccessExpiresAt returns the exp claim of the access token, or the zero time when it has none.
+func (t *Tokens) AccessExpiresAt() time.Time {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.accessExpiresAt
+}
+
 func (t *Tokens) GetRefresh() string {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
@@ -24,6 +38,7 @@ func (t *Tokens) SetAccess(access string) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	t.accessToken = access
+	t.accessExpiresAt = expiresAt(access)
 }
 
 func (t *Tokens) SetRefresh(refresh string) {
@@ -31,3 +46,25 @@ func (t *Tokens) SetRefresh(refresh string) {
 	defer t.mu.Unlock()
 	t.refreshToken = refresh
 }
+
+// expiresAt reads the exp claim of a JWT without verifying it, which only the servers can do.
+func expiresAt(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err = json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(claims.Exp, 0)
+}
@@ -5,8 +5,34 @@ CREATE TABLE IF NOT EXISTS device_data
     longitude Float64,
     altitude Float64,
     battery Float64,
-    timestamp DateTime64(0),
+    timestamp DateTime64(3),
+    measurements_number Map(LowCardinality(String), Float64),
+    measurements_bool Map(LowCardinality(String), Bool),
+    measurements_string Map(LowCardinality(String), String),
+    ingested_at DateTime64(3),
+    received_at DateTime64(3),
+    clock_skewed Bool,
+    seq UInt64
 )
 ENGINE = MergeTree()
 PARTITION BY toYYYYMM(timestamp)
 ORDER BY (timestamp, id);
+
+-- tables created before measurements were introduced
+ALTER TABLE device_data
+    ADD COLUMN IF NOT EXISTS measurements_number Map(LowCardinality(String), Float64),
+    ADD COLUMN IF NOT EXISTS measurements_bool Map(LowCardinality(String), Bool),
+    ADD COLUMN IF NOT EXISTS measurements_string Map(LowCardinality(String), String);
+
+-- tables created before ingest headers were introduced
+ALTER TABLE device_data
+    ADD COLUMN IF NOT EXISTS ingested_at DateTime64(3);
+
+-- tables created before server-side receive timestamps were introduced
+ALTER TABLE device_data
+    ADD COLUMN IF NOT EXISTS received_at DateTime64(3),
+    ADD COLUMN IF NOT EXISTS clock_skewed Bool;
+
+-- tables created before sequence numbers were introduced
+ALTER TABLE device_data
+    ADD COLUMN IF NOT EXISTS seq UInt64;
@@ -3,25 +3,35 @@ package app
 import (
 	"context"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 
 	"github.com/DangeL187/erax"
-	"github.com/eclipse/paho.mqtt.golang"
-	"github.com/google/uuid"
 
 	authInfra "ingress/internal/features/auth/infra"
 	authRuntime "ingress/internal/features/auth/runtime"
+	codecInfra "ingress/internal/features/codec/infra"
+	codecRuntime "ingress/internal/features/codec/runtime"
 	consumerRuntime "ingress/internal/features/consumer/runtime"
+	dedupInfra "ingress/internal/features/dedup/infra"
 	producerInfra "ingress/internal/features/producer/infra"
 	producerRuntime "ingress/internal/features/producer/runtime"
-	infraMqtt "ingress/internal/infra/mqtt"
+	rateLimitDomain "ingress/internal/features/ratelimit/domain"
+	rateLimitInfra "ingress/internal/features/ratelimit/infra"
+	rateLimitRuntime "ingress/internal/features/ratelimit/runtime"
+	"ingress/internal/infra/coap"
+	"ingress/internal/infra/grpcingest"
+	"ingress/internal/infra/httpingest"
 	"ingress/internal/shared/config"
+	"ingress/internal/shared/uplink"
 )
 
 type App struct {
-	msgChan chan []byte
+	msgChan chan *uplink.Message
 
 	cfg *config.Config
 
+	authConn *grpc.ClientConn
+
 	consumerLoop *consumerRuntime.ConsumerLoop
 	producerLoop *producerRuntime.ProducerLoop
 
@@ -32,7 +42,7 @@ func (a *App) Run() error {
 	ctx, cancel := context.WithCancel(context.Background())
 	a.cancel = cancel
 
-	a.producerLoop.Run(ctx, a.cfg.KafkaTopic)
+	a.producerLoop.Run(ctx)
 
 	err := a.consumerLoop.Run()
 	if err != nil {
@@ -50,6 +60,11 @@ func (a *App) Stop() {
 	a.consumerLoop.Stop()
 	close(a.msgChan)
 	a.producerLoop.Stop()
+
+	// after the ProducerLoop, which stops the auth and rate limit services using the connection
+	if err := a.authConn.Close(); err != nil {
+		zap.L().Error("failed to close auth gRPC connection", zap.Error(err))
+	}
 }
 
 func NewApp() (*App, error) {
@@ -61,41 +76,85 @@ func NewApp() (*App, error) {
 		return nil, erax.Wrap(err, "failed to load config")
 	}
 
-	app.msgChan = make(chan []byte, app.cfg.MsgChanSize)
+	app.msgChan = make(chan *uplink.Message, app.cfg.MsgChanSize)
 
 	// publisher and consumer
-	opts := mqtt.NewClientOptions().
-		AddBroker("tcp://" + app.cfg.MQTTBroker).
-		SetClientID(app.cfg.MQTTClientID + uuid.New().String())
-	mqttClient := mqtt.NewClient(opts)
-
-	publisher := infraMqtt.NewPublisher(mqttClient)
-	consumer := infraMqtt.NewConsumer(mqttClient, app.cfg.MQTTTopic)
+	mqttConsumer, publisher, err := newMQTT(app.cfg)
+	if err != nil {
+		return nil, erax.Wrap(err, "failed to create mqtt clients")
+	}
 
-	// ConsumerLoop
-	app.consumerLoop, err = consumerRuntime.NewConsumerLoop(app.cfg, app.msgChan, consumer)
+	app.authConn, err = newAuthGRPCConn(app.cfg)
 	if err != nil {
-		return nil, erax.Wrap(err, "failed to create consumer loop")
+		return nil, erax.Wrap(err, "failed to connect to auth service")
 	}
 
 	// Auth Service
-	authenticator, err := authInfra.NewGRPCAuthenticator(app.cfg.GRPCAddr)
+	tokenCache := authInfra.NewTokenCache(app.cfg.AuthCacheSize)
+	authenticator := authInfra.NewGRPCAuthenticator(app.authConn, tokenCache, app.cfg.JWTIssuer, app.cfg.JWTAudience)
+	disabledSource := authInfra.NewGRPCDisabledSource(app.authConn)
+
+	authService, err := authRuntime.NewAuthService(app.cfg.DisabledDevicesRefresh, authenticator, disabledSource,
+		publisher)
 	if err != nil {
-		return nil, erax.Wrap(err, "failed to create authenticator")
+		return nil, erax.Wrap(err, "failed to create auth service")
 	}
 
-	authService, err := authRuntime.NewAuthService(authenticator, publisher)
+	// Rate Limit Service
+	limiter := rateLimitInfra.NewTokenBucketLimiter(rateLimitDomain.Limit{
+		Rate:  app.cfg.RateLimit,
+		Burst: app.cfg.RateBurst,
+	}, app.cfg.RateLimiterSize)
+
+	limitsSource := rateLimitInfra.NewGRPCLimitsSource(app.authConn)
+
+	rateLimitService := rateLimitRuntime.NewRateLimitService(app.cfg.RateLimitRefresh, limiter, limitsSource, publisher)
+
+	codecRegistry := codecRuntime.NewCodecRegistry(app.cfg.BatchMaxSize, codecInfra.JSONCodec{},
+		codecInfra.ProtobufCodec{}, codecInfra.CBORCodec{}, codecInfra.MessagePackCodec{})
+
+	// ConsumerLoop
+	consumers := consumerGroup{mqttConsumer}
+	if app.cfg.HTTPAddr != "" {
+		httpTLS, err := app.cfg.HTTPTLS.ServerTLS()
+		if err != nil {
+			return nil, erax.Wrap(err, "failed to load http ingest tls config")
+		}
+
+		consumers = append(consumers, httpingest.NewConsumer(app.cfg.HTTPAddr, httpTLS, codecRegistry, authService,
+			rateLimitService, app.cfg.HTTPMaxBodyBytes))
+	}
+
+	if app.cfg.GRPCIngestAddr != "" {
+		grpcIngestTLS, err := app.cfg.GRPCIngestTLS.ServerTLS()
+		if err != nil {
+			return nil, erax.Wrap(err, "failed to load grpc ingest tls config")
+		}
+
+		consumers = append(consumers, grpcingest.NewConsumer(app.cfg.GRPCIngestAddr, grpcIngestTLS, authService,
+			rateLimitService))
+	}
+
+	if app.cfg.CoAPAddr != "" {
+		consumers = append(consumers, coap.NewConsumer(app.cfg.CoAPAddr, codecRegistry, authService,
+			rateLimitService, app.cfg.CoAPMaxBodyBytes))
+	}
+
+	app.consumerLoop, err = consumerRuntime.NewConsumerLoop(app.cfg, app.msgChan, consumers)
 	if err != nil {
-		return nil, erax.Wrap(err, "failed to create auth service")
+		return nil, erax.Wrap(err, "failed to create consumer loop")
 	}
 
 	// ProducerLoop
-	producer, err := producerInfra.NewKafkaProducer(app.cfg.KafkaBroker)
+	producer, err := producerInfra.NewKafkaProducer(app.cfg)
 	if err != nil {
 		return nil, erax.Wrap(err, "failed to create producer")
 	}
 
-	app.producerLoop = producerRuntime.NewProducerLoop(app.msgChan, authService, producer)
+	sequenceTracker := dedupInfra.NewSequenceTracker(app.cfg.SeqWindow, app.cfg.SeqTrackerSize)
+
+	app.producerLoop = producerRuntime.NewProducerLoop(app.cfg, app.msgChan, authService, rateLimitService,
+		codecRegistry, sequenceTracker, producer)
 
 	return app, nil
 }
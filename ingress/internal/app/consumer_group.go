@@ -0,0 +1,31 @@
+package app
+
+import (
+	"errors"
+
+	"github.com/DangeL187/erax"
+
+	"ingress/internal/shared/uplink"
+)
+
+// consumerGroup runs several consumers as one, so that every transport feeds the same ConsumerLoop.
+type consumerGroup []consumer
+
+func (g consumerGroup) Run(messageHandler func(msg *uplink.Message) bool) error {
+	for _, c := range g {
+		if err := c.Run(messageHandler); err != nil {
+			return erax.Wrap(err, "failed to run consumer")
+		}
+	}
+
+	return nil
+}
+
+func (g consumerGroup) Stop() error {
+	var errs []error
+	for _, c := range g {
+		errs = append(errs, c.Stop())
+	}
+
+	return errors.Join(errs...)
+}
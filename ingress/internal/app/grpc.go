@@ -0,0 +1,33 @@
+package app
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/DangeL187/erax"
+
+	"ingress/internal/shared/config"
+)
+
+// newAuthGRPCConn creates the connection to the auth service shared by the authenticator, the disabled devices
+// source and the rate limits source.
+func newAuthGRPCConn(cfg *config.Config) (*grpc.ClientConn, error) {
+	tlsConfig, err := cfg.AuthGRPCTLS.ClientTLS()
+	if err != nil {
+		return nil, erax.Wrap(err, "failed to load auth gRPC tls config")
+	}
+
+	// a nil tlsConfig keeps the connection in plaintext
+	creds := insecure.NewCredentials()
+	if tlsConfig != nil {
+		creds = credentials.NewTLS(tlsConfig)
+	}
+
+	conn, err := grpc.NewClient(cfg.GRPCAddr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, erax.Wrap(err, "failed to create grpc client")
+	}
+
+	return conn, nil
+}
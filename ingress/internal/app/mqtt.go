@@ -0,0 +1,71 @@
+package app
+
+import (
+	"github.com/DangeL187/erax"
+	"github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+
+	infraMqtt "ingress/internal/infra/mqtt"
+	"ingress/internal/infra/mqtt5"
+	"ingress/internal/shared/config"
+	"ingress/internal/shared/uplink"
+)
+
+type consumer interface {
+	Run(messageHandler func(msg *uplink.Message) bool) error
+	Stop() error
+}
+
+type mqttPublisher interface {
+	Publish(topic string, payload any) error
+}
+
+// newMQTT creates a consumer and a publisher sharing one connection of the configured MQTT version.
+func newMQTT(cfg *config.Config) (consumer, mqttPublisher, error) {
+	tlsConfig, err := cfg.MQTTTLS.ClientTLS()
+	if err != nil {
+		return nil, nil, erax.Wrap(err, "failed to load mqtt tls config")
+	}
+
+	// with the ack overflow policy, messages are acknowledged only once they are enqueued, and the rest are
+	// redelivered from the session when ingress reconnects
+	var consumerQoS byte
+	manualAck := cfg.OverflowPolicy == config.OverflowPolicyAck
+	if manualAck {
+		consumerQoS = 1
+	}
+
+	// a persistent session is bound to the client ID, so it must survive restarts
+	persistent := manualAck || cfg.MQTTVersion == config.MQTTVersion5 && cfg.MQTTSessionExpiry > 0
+	clientID := cfg.MQTTClientID + uuid.New().String()
+	if persistent {
+		clientID = cfg.MQTTClientID + "-" + cfg.InstanceID
+	}
+
+	if cfg.MQTTVersion == config.MQTTVersion5 {
+		conn, err := mqtt5.NewConnection(cfg.MQTTBroker, tlsConfig, clientID, cfg.MQTTUsername, cfg.MQTTPassword,
+			cfg.MQTTSessionExpiry, manualAck)
+		if err != nil {
+			return nil, nil, erax.Wrap(err, "failed to create mqtt v5 connection")
+		}
+
+		return mqtt5.NewConsumer(conn, cfg.MQTTTopic, cfg.MQTTShareGroup, consumerQoS, cfg.OverflowTimeout),
+			mqtt5.NewPublisher(conn), nil
+	}
+
+	opts := mqtt.NewClientOptions().
+		SetClientID(clientID).
+		SetCleanSession(!persistent).
+		SetUsername(cfg.MQTTUsername).
+		SetPassword(cfg.MQTTPassword).
+		SetAutoAckDisabled(manualAck)
+	if tlsConfig != nil {
+		opts.AddBroker("ssl://" + cfg.MQTTBroker).SetTLSConfig(tlsConfig)
+	} else {
+		opts.AddBroker("tcp://" + cfg.MQTTBroker)
+	}
+	mqttClient := mqtt.NewClient(opts)
+
+	return infraMqtt.NewConsumer(mqttClient, cfg.MQTTTopic, cfg.MQTTShareGroup, consumerQoS, manualAck,
+		cfg.OverflowTimeout), infraMqtt.NewPublisher(mqttClient), nil
+}
@@ -6,112 +6,289 @@ import (
 	"crypto/x509"
 	"encoding/base64"
 	"errors"
+	"go.uber.org/zap"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"math"
+	"sync"
+	"time"
 
 	"github.com/DangeL187/erax"
 	"github.com/golang-jwt/jwt/v5"
 
 	pb "ingress/internal/infra/grpc/proto/auth"
+	"ingress/internal/shared/auth"
 )
 
+const (
+	keysMaxAge             = 5 * time.Minute
+	keysMinRefreshInterval = 10 * time.Second
+)
+
+var (
+	errUnknownKeyID = errors.New("unknown key ID")
+	errNoKeys       = errors.New("no public keys fetched from the auth service yet")
+)
+
+type verificationKey struct {
+	key       ed25519.PublicKey
+	retiresAt time.Time
+}
+
+func (k verificationKey) retired(now time.Time) bool {
+	return !k.retiresAt.IsZero() && !now.Before(k.retiresAt)
+}
+
 type GRPCAuthenticator struct {
 	grpcAuthClient pb.AuthServiceClient
-	grpcClientConn *grpc.ClientConn
 
-	publicKey ed25519.PublicKey
+	parser *jwt.Parser
+	// claimsValidator checks the iss and aud claims against the auth service's JWT_ISSUER and JWT_AUDIENCE
+	claimsValidator *jwt.Validator
+
+	keysMu          sync.RWMutex
+	keys            map[string]verificationKey
+	keysRefreshedAt time.Time
+
+	refreshMu          sync.Mutex
+	lastRefreshAttempt time.Time
+
+	tokenCache *TokenCache
 }
 
-func (a *GRPCAuthenticator) Auth(ctx context.Context, deviceToken string) error {
-	if a.publicKey != nil {
-		if err := a.verifyToken(deviceToken); err == nil {
-			return nil
-		}
+// Auth returns the identity deviceToken was issued to, from the TokenCache when it was verified before.
+func (a *GRPCAuthenticator) Auth(ctx context.Context, deviceToken string) (auth.Identity, error) {
+	if identity, ok := a.tokenCache.Get(deviceToken); ok {
+		return identity, nil
+	}
 
-		if err := a.updatePublicJWT(ctx); err != nil {
-			return erax.Wrap(err, "failed to update public JWT")
+	if a.keysAge() > keysMaxAge {
+		if err := a.refreshKeys(ctx); err != nil {
+			zap.L().Warn("failed to refresh public keys, using the known ones", zap.Error(err))
 		}
+	}
 
-		if err := a.verifyToken(deviceToken); err == nil {
-			return nil
+	identity, err := a.verifyToken(deviceToken)
+	if errors.Is(err, errUnknownKeyID) {
+		if err = a.refreshKeys(ctx); err != nil {
+			return auth.Identity{}, erax.Wrap(err, "failed to refresh public keys")
 		}
+		identity, err = a.verifyToken(deviceToken)
+	}
+	if err != nil {
+		return auth.Identity{}, erax.Wrap(err, "failed to verify device token")
+	}
+	a.tokenCache.Set(deviceToken, identity)
+
+	return identity, nil
+}
 
-		return errors.New("unauthorized after refreshing public JWT")
+func (a *GRPCAuthenticator) keysAge() time.Duration {
+	a.keysMu.RLock()
+	defer a.keysMu.RUnlock()
+
+	if a.keys == nil {
+		return time.Duration(math.MaxInt64)
 	}
 
-	if err := a.updatePublicJWT(ctx); err != nil {
-		return erax.Wrap(err, "failed to update public JWT")
+	return time.Since(a.keysRefreshedAt)
+}
+
+// candidateKeys returns the key named by kid or, for tokens issued before key rotation was introduced, which carry
+// no kid, every key that has not retired.
+func (a *GRPCAuthenticator) candidateKeys(kid string, now time.Time) ([]verificationKey, error) {
+	a.keysMu.RLock()
+	defer a.keysMu.RUnlock()
+
+	if len(a.keys) == 0 {
+		return nil, errNoKeys
 	}
 
-	if err := a.verifyToken(deviceToken); err != nil {
-		return erax.Wrap(err, "failed to verify device token")
+	if kid != "" {
+		key, ok := a.keys[kid]
+		if !ok {
+			return nil, errUnknownKeyID
+		}
+		if key.retired(now) {
+			return nil, errors.New("key is retired")
+		}
+		return []verificationKey{key}, nil
 	}
 
-	return nil
+	keys := make([]verificationKey, 0, len(a.keys))
+	for _, key := range a.keys {
+		if !key.retired(now) {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errUnknownKeyID
+	}
+
+	return keys, nil
 }
 
-func (a *GRPCAuthenticator) Close() error {
-	err := a.grpcClientConn.Close()
+// refreshKeys fetches the key set from the auth service at most once per keysMinRefreshInterval,
+// so that tokens with made-up key IDs cannot flood it with requests.
+func (a *GRPCAuthenticator) refreshKeys(ctx context.Context) error {
+	a.refreshMu.Lock()
+	defer a.refreshMu.Unlock()
+
+	if time.Since(a.lastRefreshAttempt) < keysMinRefreshInterval {
+		return nil
+	}
+	a.lastRefreshAttempt = time.Now()
+
+	resp, err := a.grpcAuthClient.GetPublicKeys(ctx, &pb.GetPublicKeysRequest{})
 	if err != nil {
-		return erax.Wrap(err, "failed to close gRPC client connection")
+		return erax.Wrap(err, "failed to get public keys")
 	}
+	if len(resp.Keys) == 0 {
+		return errors.New("auth service returned no public keys")
+	}
+
+	keys := make(map[string]verificationKey, len(resp.Keys))
+	for _, k := range resp.Keys {
+		pubKey, err := parsePublicKey(k.PublicKey)
+		if err != nil {
+			return erax.Wrap(err, "failed to parse public key "+k.KeyId)
+		}
+
+		key := verificationKey{key: pubKey}
+		if k.RetiresAt != 0 {
+			key.retiresAt = time.Unix(k.RetiresAt, 0)
+		}
+		keys[k.KeyId] = key
+	}
+
+	a.keysMu.Lock()
+	removed := false
+	for id := range a.keys {
+		if _, ok := keys[id]; !ok {
+			removed = true
+			break
+		}
+	}
+	a.keys = keys
+	a.keysRefreshedAt = time.Now()
+	a.keysMu.Unlock()
+
+	if removed {
+		// tokens verified with a removed key must not outlive it
+		a.tokenCache.Purge()
+	}
+
 	return nil
 }
 
-func (a *GRPCAuthenticator) updatePublicJWT(ctx context.Context) error {
-	resp, err := a.grpcAuthClient.GetPublicKey(ctx, &pb.GetPublicKeyRequest{})
+// verifyToken marks errors caused by the token itself with auth.ErrSignatureInvalid, and expired tokens with
+// auth.ErrTokenExpired; the remaining errors say nothing about the token.
+func (a *GRPCAuthenticator) verifyToken(tokenString string) (auth.Identity, error) {
+	unverified, _, err := jwt.NewParser().ParseUnverified(tokenString, jwt.MapClaims{})
 	if err != nil {
-		return erax.Wrap(err, "failed to get public key")
+		return auth.Identity{}, erax.WrapWithError(err, auth.ErrSignatureInvalid, "invalid token")
 	}
+	kid, _ := unverified.Header["kid"].(string)
 
-	pubDER, err := base64.StdEncoding.DecodeString(resp.PublicKey)
+	keys, err := a.candidateKeys(kid, time.Now())
+	if errors.Is(err, errNoKeys) {
+		return auth.Identity{}, erax.Wrap(err, "failed to verify token")
+	}
 	if err != nil {
-		return errors.New("failed to decode base64 public key: " + err.Error())
+		return auth.Identity{}, erax.WrapWithError(err, auth.ErrSignatureInvalid, "invalid token")
 	}
 
-	pubIfc, err := x509.ParsePKIXPublicKey(pubDER)
+	var key verificationKey
+	var claims jwt.MapClaims
+	for _, key = range keys {
+		claims, err = a.parseToken(tokenString, key.key)
+		if !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+			break // verified, or signed with this key but invalid otherwise
+		}
+	}
+
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return auth.Identity{}, erax.WrapWithError(err, auth.ErrTokenExpired, "invalid token")
+	}
 	if err != nil {
-		return errors.New("failed to parse DER public key: " + err.Error())
+		return auth.Identity{}, erax.WrapWithError(err, auth.ErrSignatureInvalid, "invalid token")
 	}
 
-	pubKey, ok := pubIfc.(ed25519.PublicKey)
-	if !ok {
-		return errors.New("not an ed25519 public key")
+	// tokens without a kid were issued before key rotation and the iss and aud claims were introduced, so they are
+	// accepted without either until they expire; any other token must carry both
+	_, hasIssuer := claims["iss"]
+	_, hasAudience := claims["aud"]
+	if kid != "" || hasIssuer || hasAudience {
+		if err = a.claimsValidator.Validate(claims); err != nil {
+			return auth.Identity{}, erax.WrapWithError(err, auth.ErrSignatureInvalid, "invalid token")
+		}
+	}
+
+	// refresh tokens are signed with the same key, so only the typ claim keeps them from standing in for access tokens
+	if typ, _ := claims["typ"].(string); typ != "access" {
+		return auth.Identity{}, erax.Wrap(auth.ErrSignatureInvalid, "not an access token")
 	}
 
-	a.publicKey = pubKey
+	deviceID, ok := claims["device_id"].(string)
+	if !ok || deviceID == "" {
+		return auth.Identity{}, erax.Wrap(auth.ErrSignatureInvalid, "device_id claim is missing or not a string")
+	}
 
-	return nil
+	identity := auth.Identity{DeviceID: deviceID}
+
+	exp, err := claims.GetExpirationTime()
+	if err == nil && exp != nil {
+		identity.ExpiresAt = exp.Time
+	}
+	if !key.retiresAt.IsZero() && key.retiresAt.Before(identity.ExpiresAt) {
+		identity.ExpiresAt = key.retiresAt
+	}
+
+	return identity, nil
 }
 
-func (a *GRPCAuthenticator) verifyToken(tokenString string) error {
-	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
+func (a *GRPCAuthenticator) parseToken(tokenString string, key ed25519.PublicKey) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	token, err := a.parser.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
 		if t.Method != jwt.SigningMethodEdDSA {
 			return nil, errors.New("invalid signing method")
 		}
-		return a.publicKey, nil
+		return key, nil
 	})
-
 	if err != nil {
-		return erax.Wrap(err, "invalid token")
+		return nil, err
 	}
 	if !token.Valid {
-		return errors.New("invalid token")
+		return nil, errors.New("invalid token")
 	}
 
-	return nil
+	return claims, nil
 }
 
-func NewGRPCAuthenticator(grpcAddr string) (*GRPCAuthenticator, error) {
-	a := &GRPCAuthenticator{}
+func parsePublicKey(publicKeyB64 string) (ed25519.PublicKey, error) {
+	pubDER, err := base64.StdEncoding.DecodeString(publicKeyB64)
+	if err != nil {
+		return nil, errors.New("failed to decode base64 public key: " + err.Error())
+	}
 
-	var err error
-	a.grpcClientConn, err = grpc.NewClient(grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	pubIfc, err := x509.ParsePKIXPublicKey(pubDER)
 	if err != nil {
-		return nil, erax.Wrap(err, "failed to create grpc client")
+		return nil, errors.New("failed to parse DER public key: " + err.Error())
 	}
 
-	a.grpcAuthClient = pb.NewAuthServiceClient(a.grpcClientConn)
+	pubKey, ok := pubIfc.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.New("not an ed25519 public key")
+	}
+
+	return pubKey, nil
+}
 
-	return a, nil
+// NewGRPCAuthenticator fetches keys over conn, which the caller owns and closes.
+func NewGRPCAuthenticator(conn *grpc.ClientConn, tokenCache *TokenCache, issuer, audience string) *GRPCAuthenticator {
+	return &GRPCAuthenticator{
+		grpcAuthClient:  pb.NewAuthServiceClient(conn),
+		parser:          jwt.NewParser(),
+		claimsValidator: jwt.NewValidator(jwt.WithIssuer(issuer), jwt.WithAudience(audience)),
+		tokenCache:      tokenCache,
+	}
 }
@@ -0,0 +1,36 @@
+package infra
+
+import (
+	"context"
+	"google.golang.org/grpc"
+
+	"github.com/DangeL187/erax"
+
+	pb "ingress/internal/infra/grpc/proto/auth"
+)
+
+// GRPCDisabledSource fetches the devices disabled in the auth service.
+type GRPCDisabledSource struct {
+	grpcAuthClient pb.AuthServiceClient
+}
+
+func (s *GRPCDisabledSource) GetDisabled(ctx context.Context) (map[string]struct{}, error) {
+	resp, err := s.grpcAuthClient.GetDisabledDevices(ctx, &pb.GetDisabledDevicesRequest{})
+	if err != nil {
+		return nil, erax.Wrap(err, "failed to get disabled devices")
+	}
+
+	disabled := make(map[string]struct{}, len(resp.DeviceIds))
+	for _, deviceID := range resp.DeviceIds {
+		disabled[deviceID] = struct{}{}
+	}
+
+	return disabled, nil
+}
+
+// NewGRPCDisabledSource uses conn, which the caller owns and closes.
+func NewGRPCDisabledSource(conn *grpc.ClientConn) *GRPCDisabledSource {
+	return &GRPCDisabledSource{
+		grpcAuthClient: pb.NewAuthServiceClient(conn),
+	}
+}
@@ -0,0 +1,77 @@
+package infra
+
+import (
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"ingress/internal/infra/metrics"
+	"ingress/internal/shared/auth"
+)
+
+type TokenCache struct {
+	mu      sync.RWMutex
+	entries map[[sha256.Size]byte]auth.Identity
+	maxSize int
+}
+
+func (tc *TokenCache) Get(deviceToken string) (auth.Identity, bool) {
+	key := sha256.Sum256([]byte(deviceToken))
+
+	tc.mu.RLock()
+	identity, ok := tc.entries[key]
+	tc.mu.RUnlock()
+
+	if !ok {
+		metrics.AuthCacheMisses.Inc()
+		return auth.Identity{}, false
+	}
+
+	if !time.Now().Before(identity.ExpiresAt) {
+		tc.mu.Lock()
+		if current, exists := tc.entries[key]; exists && current == identity {
+			delete(tc.entries, key)
+		}
+		tc.mu.Unlock()
+
+		metrics.AuthCacheMisses.Inc()
+		return auth.Identity{}, false
+	}
+
+	metrics.AuthCacheHits.Inc()
+	return identity, true
+}
+
+func (tc *TokenCache) Set(deviceToken string, identity auth.Identity) {
+	if !time.Now().Before(identity.ExpiresAt) {
+		return
+	}
+
+	key := sha256.Sum256([]byte(deviceToken))
+
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	if _, exists := tc.entries[key]; !exists && len(tc.entries) >= tc.maxSize {
+		// map iteration order is random, so this evicts an arbitrary entry
+		for k := range tc.entries {
+			delete(tc.entries, k)
+			break
+		}
+	}
+
+	tc.entries[key] = identity
+}
+
+func (tc *TokenCache) Purge() {
+	tc.mu.Lock()
+	clear(tc.entries)
+	tc.mu.Unlock()
+}
+
+func NewTokenCache(maxSize int) *TokenCache {
+	return &TokenCache{
+		entries: make(map[[sha256.Size]byte]auth.Identity, maxSize),
+		maxSize: maxSize,
+	}
+}
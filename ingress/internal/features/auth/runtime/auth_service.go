@@ -4,65 +4,184 @@ import (
 	"context"
 	"encoding/json"
 	"go.uber.org/zap"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/DangeL187/erax"
+
+	"ingress/internal/infra/metrics"
+	"ingress/internal/shared/auth"
+	"ingress/internal/shared/uplink"
 )
 
 type authResponse struct {
 	DeviceID string
-	ErrorMsg string
+	Response auth.ErrorResponse
+	Reply    uplink.Reply
 }
 
 type authenticator interface {
-	Auth(ctx context.Context, deviceToken string) error
-	Close() error
+	Auth(ctx context.Context, deviceToken string) (auth.Identity, error)
+}
+
+type disabledSource interface {
+	GetDisabled(ctx context.Context) (map[string]struct{}, error)
 }
 
 type publisher interface {
 	Publish(topic string, payload any) error
 }
 
+// responder is implemented by publishers that can attach correlation data to a response (MQTT v5).
+type responder interface {
+	PublishResponse(topic string, correlationData []byte, payload any) error
+}
+
+// AuthService verifies device tokens, rejects devices disabled in the auth service and reports auth errors to
+// devices.
 type AuthService struct {
 	errRespChan chan authResponse
 	errRespWg   sync.WaitGroup
+	refreshWg   sync.WaitGroup
+
+	refreshInterval time.Duration
+	disabled        atomic.Pointer[map[string]struct{}]
 
-	authenticator authenticator
-	publisher     publisher
+	authenticator  authenticator
+	disabledSource disabledSource
+	publisher      publisher
 }
 
 func (as *AuthService) Run(ctx context.Context) {
+	as.runRefresher(ctx)
 	as.runErrRespWorkers(ctx, 1)
 }
 
 func (as *AuthService) Stop() {
-	err := as.authenticator.Close()
-	if err != nil {
-		zap.L().Error("failed to close authenticator", zap.Error(err))
-	}
+	as.refreshWg.Wait()
 
 	close(as.errRespChan)
 
 	as.errRespWg.Wait()
 }
 
-func (as *AuthService) Auth(deviceID, deviceToken string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-	defer cancel()
-
-	err := as.authenticator.Auth(ctx, deviceToken)
+// Auth checks that deviceToken belongs to deviceID. Errors are reported to the device as an auth.ErrorResponse on
+// reply.Topic when it set one, or on devices/<id>/auth_response otherwise.
+func (as *AuthService) Auth(deviceID, deviceToken string, reply uplink.Reply) error {
+	identity, err := as.Verify(deviceToken)
 	if err != nil {
 		as.errRespChan <- authResponse{
 			DeviceID: deviceID,
-			ErrorMsg: err.Error(),
+			Response: auth.NewErrorResponse(auth.CodeOf(err), reply.CorrelationID()),
+			Reply:    reply,
 		}
 		return erax.Wrap(err, "failed to auth device")
 	}
 
+	if identity.DeviceID != deviceID {
+		as.reportMismatch(identity.DeviceID, reply)
+		return erax.Wrap(auth.ErrIdentityMismatch, "failed to auth device")
+	}
+
 	return nil
 }
 
+// Match checks a message whose token a consumer already verified for verifiedID, without verifying it again: the
+// message must be for that device and must not carry a token other than verifiedToken. Mismatches are reported to
+// the device like in Auth.
+func (as *AuthService) Match(verifiedID, verifiedToken, deviceID, deviceToken string, reply uplink.Reply) error {
+	if as.isDisabled(verifiedID) {
+		as.errRespChan <- authResponse{
+			DeviceID: verifiedID,
+			Response: auth.NewErrorResponse(auth.CodeDeviceDisabled, reply.CorrelationID()),
+			Reply:    reply,
+		}
+		return erax.Wrap(auth.ErrDeviceDisabled, "failed to match device")
+	}
+
+	if deviceID != verifiedID || deviceToken != verifiedToken {
+		as.reportMismatch(verifiedID, reply)
+		return erax.Wrap(auth.ErrIdentityMismatch, "failed to match device")
+	}
+
+	return nil
+}
+
+// reportMismatch sends the identity_mismatch response to the token owner, not to the device it tried to impersonate.
+func (as *AuthService) reportMismatch(ownerID string, reply uplink.Reply) {
+	as.errRespChan <- authResponse{
+		DeviceID: ownerID,
+		Response: auth.NewErrorResponse(auth.CodeIdentityMismatch, reply.CorrelationID()),
+		Reply:    reply,
+	}
+}
+
+// Verify returns the identity deviceToken was issued to without reporting errors to the device, for consumers that
+// answer the device themselves (e.g. with an HTTP status). The authenticator caches verified tokens.
+func (as *AuthService) Verify(deviceToken string) (auth.Identity, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	identity, err := as.authenticator.Auth(ctx, deviceToken)
+	if err != nil {
+		return auth.Identity{}, err
+	}
+	if as.isDisabled(identity.DeviceID) {
+		return identity, auth.ErrDeviceDisabled
+	}
+
+	return identity, nil
+}
+
+func (as *AuthService) isDisabled(deviceID string) bool {
+	disabled := as.disabled.Load()
+	if disabled == nil {
+		return false
+	}
+
+	_, ok := (*disabled)[deviceID]
+	return ok
+}
+
+// runRefresher keeps the disabled devices in sync with the auth service; until the first refresh succeeds no device
+// is considered disabled.
+func (as *AuthService) runRefresher(ctx context.Context) {
+	as.refreshWg.Add(1)
+
+	go func() {
+		defer as.refreshWg.Done()
+
+		ticker := time.NewTicker(as.refreshInterval)
+		defer ticker.Stop()
+
+		for {
+			as.refresh(ctx)
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (as *AuthService) refresh(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	disabled, err := as.disabledSource.GetDisabled(ctx)
+	if err != nil {
+		zap.L().Warn("failed to refresh disabled devices, using the known ones", zap.Error(err))
+		return
+	}
+
+	as.disabled.Store(&disabled)
+	metrics.DisabledDevices.Set(float64(len(disabled)))
+}
+
 func (as *AuthService) runErrRespWorkers(ctx context.Context, workerCount int) {
 	as.errRespWg.Add(workerCount)
 
@@ -75,13 +194,12 @@ func (as *AuthService) runErrRespWorkers(ctx context.Context, workerCount int) {
 					if !ok {
 						return
 					}
-					payload, err := json.Marshal(map[string]string{"error": job.ErrorMsg})
+					payload, err := json.Marshal(job.Response)
 					if err != nil {
 						zap.L().Error("failed to marshal auth response", zap.Error(err))
 						continue
 					}
-					topic := "devices/" + job.DeviceID + "/auth_response"
-					err = as.publisher.Publish(topic, payload)
+					err = as.respond(job, payload)
 					if err != nil {
 						zap.L().Error("failed to publish auth response", zap.Error(err))
 						continue
@@ -94,11 +212,33 @@ func (as *AuthService) runErrRespWorkers(ctx context.Context, workerCount int) {
 	}
 }
 
-func NewAuthService(authenticator authenticator, publisher publisher) (*AuthService, error) {
+// respond answers on the message's own transport when it has one. Otherwise it publishes to the response topic the
+// device asked for, as long as it is one of its own topics, so that auth errors cannot be aimed at other devices.
+func (as *AuthService) respond(job authResponse, payload []byte) error {
+	if job.Reply.Respond != nil {
+		return job.Reply.Respond(job.Response)
+	}
+
+	deviceTopic := "devices/" + job.DeviceID + "/"
+	if job.Reply.Topic == "" || !strings.HasPrefix(job.Reply.Topic, deviceTopic) {
+		return as.publisher.Publish(deviceTopic+"auth_response", payload)
+	}
+
+	if r, ok := as.publisher.(responder); ok {
+		return r.PublishResponse(job.Reply.Topic, job.Reply.CorrelationData, payload)
+	}
+
+	return as.publisher.Publish(job.Reply.Topic, payload)
+}
+
+func NewAuthService(refreshInterval time.Duration, authenticator authenticator, disabledSource disabledSource,
+	publisher publisher) (*AuthService, error) {
 	s := &AuthService{
-		errRespChan:   make(chan authResponse, 1024),
-		authenticator: authenticator,
-		publisher:     publisher,
+		errRespChan:     make(chan authResponse, 1024),
+		refreshInterval: refreshInterval,
+		authenticator:   authenticator,
+		disabledSource:  disabledSource,
+		publisher:       publisher,
 	}
 
 	return s, nil
@@ -0,0 +1,85 @@
+package infra
+
+import (
+	"strconv"
+
+	"github.com/DangeL187/erax"
+	"github.com/fxamacker/cbor/v2"
+
+	"ingress/internal/shared/uplink"
+)
+
+type cborTelemetry struct {
+	ID        string  `cbor:"id"`
+	Token     string  `cbor:"token"`
+	Latitude  float64 `cbor:"latitude"`
+	Longitude float64 `cbor:"longitude"`
+	Altitude  float64 `cbor:"altitude"`
+	Battery   float64 `cbor:"battery"`
+	Timestamp int64   `cbor:"timestamp"`
+	Seq       uint64  `cbor:"seq"`
+
+	Measurements map[string]any `cbor:"measurements"`
+}
+
+type cborBatch struct {
+	ID       string          `cbor:"id"`
+	Token    string          `cbor:"token"`
+	Readings []cborTelemetry `cbor:"readings"`
+}
+
+type CBORCodec struct{}
+
+func (CBORCodec) Name() string {
+	return "cbor"
+}
+
+func (CBORCodec) ContentTypes() []string {
+	return []string{"application/cbor"}
+}
+
+func (CBORCodec) Decode(payload []byte) (uplink.Telemetry, error) {
+	var data cborTelemetry
+	if err := cbor.Unmarshal(payload, &data); err != nil {
+		return uplink.Telemetry{}, erax.Wrap(err, "failed to unmarshal cbor payload")
+	}
+
+	return data.telemetry()
+}
+
+func (CBORCodec) DecodeBatch(payload []byte) (uplink.Batch, error) {
+	var data cborBatch
+	if err := cbor.Unmarshal(payload, &data); err != nil {
+		return uplink.Batch{}, erax.Wrap(err, "failed to unmarshal cbor batch")
+	}
+
+	readings := make([]uplink.Telemetry, len(data.Readings))
+	for i, reading := range data.Readings {
+		telemetry, err := reading.telemetry()
+		if err != nil {
+			return uplink.Batch{}, erax.Wrap(err, "failed to decode cbor batch reading "+strconv.Itoa(i))
+		}
+		readings[i] = telemetry
+	}
+
+	return uplink.Batch{ID: data.ID, Token: data.Token, Readings: readings}, nil
+}
+
+func (t cborTelemetry) telemetry() (uplink.Telemetry, error) {
+	measurements, err := uplink.MeasurementsFromMap(t.Measurements)
+	if err != nil {
+		return uplink.Telemetry{}, erax.Wrap(err, "failed to decode cbor measurements")
+	}
+
+	return uplink.Telemetry{
+		ID:           t.ID,
+		Token:        t.Token,
+		Latitude:     t.Latitude,
+		Longitude:    t.Longitude,
+		Altitude:     t.Altitude,
+		Battery:      t.Battery,
+		Timestamp:    t.Timestamp,
+		Seq:          t.Seq,
+		Measurements: measurements,
+	}, nil
+}
@@ -0,0 +1,61 @@
+package infra
+
+import (
+	"encoding/json"
+
+	"github.com/DangeL187/erax"
+
+	"ingress/internal/shared/uplink"
+)
+
+type jsonTelemetry struct {
+	ID        string  `json:"id"`
+	Token     string  `json:"token"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Altitude  float64 `json:"altitude"`
+	Battery   float64 `json:"battery"`
+	Timestamp int64   `json:"timestamp"`
+	Seq       uint64  `json:"seq"`
+
+	Measurements map[string]uplink.Measurement `json:"measurements"`
+}
+
+type jsonBatch struct {
+	ID       string          `json:"id"`
+	Token    string          `json:"token"`
+	Readings []jsonTelemetry `json:"readings"`
+}
+
+type JSONCodec struct{}
+
+func (JSONCodec) Name() string {
+	return "json"
+}
+
+func (JSONCodec) ContentTypes() []string {
+	return []string{"application/json"}
+}
+
+func (JSONCodec) Decode(payload []byte) (uplink.Telemetry, error) {
+	var data jsonTelemetry
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return uplink.Telemetry{}, erax.Wrap(err, "failed to unmarshal json payload")
+	}
+
+	return uplink.Telemetry(data), nil
+}
+
+func (JSONCodec) DecodeBatch(payload []byte) (uplink.Batch, error) {
+	var data jsonBatch
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return uplink.Batch{}, erax.Wrap(err, "failed to unmarshal json batch")
+	}
+
+	readings := make([]uplink.Telemetry, len(data.Readings))
+	for i, reading := range data.Readings {
+		readings[i] = uplink.Telemetry(reading)
+	}
+
+	return uplink.Batch{ID: data.ID, Token: data.Token, Readings: readings}, nil
+}
@@ -0,0 +1,85 @@
+package infra
+
+import (
+	"strconv"
+
+	"github.com/DangeL187/erax"
+	"github.com/vmihailenco/msgpack/v5"
+
+	"ingress/internal/shared/uplink"
+)
+
+type msgpackTelemetry struct {
+	ID        string  `msgpack:"id"`
+	Token     string  `msgpack:"token"`
+	Latitude  float64 `msgpack:"latitude"`
+	Longitude float64 `msgpack:"longitude"`
+	Altitude  float64 `msgpack:"altitude"`
+	Battery   float64 `msgpack:"battery"`
+	Timestamp int64   `msgpack:"timestamp"`
+	Seq       uint64  `msgpack:"seq"`
+
+	Measurements map[string]any `msgpack:"measurements"`
+}
+
+type msgpackBatch struct {
+	ID       string             `msgpack:"id"`
+	Token    string             `msgpack:"token"`
+	Readings []msgpackTelemetry `msgpack:"readings"`
+}
+
+type MessagePackCodec struct{}
+
+func (MessagePackCodec) Name() string {
+	return "msgpack"
+}
+
+func (MessagePackCodec) ContentTypes() []string {
+	return []string{"application/msgpack", "application/x-msgpack", "application/vnd.msgpack"}
+}
+
+func (MessagePackCodec) Decode(payload []byte) (uplink.Telemetry, error) {
+	var data msgpackTelemetry
+	if err := msgpack.Unmarshal(payload, &data); err != nil {
+		return uplink.Telemetry{}, erax.Wrap(err, "failed to unmarshal msgpack payload")
+	}
+
+	return data.telemetry()
+}
+
+func (MessagePackCodec) DecodeBatch(payload []byte) (uplink.Batch, error) {
+	var data msgpackBatch
+	if err := msgpack.Unmarshal(payload, &data); err != nil {
+		return uplink.Batch{}, erax.Wrap(err, "failed to unmarshal msgpack batch")
+	}
+
+	readings := make([]uplink.Telemetry, len(data.Readings))
+	for i, reading := range data.Readings {
+		telemetry, err := reading.telemetry()
+		if err != nil {
+			return uplink.Batch{}, erax.Wrap(err, "failed to decode msgpack batch reading "+strconv.Itoa(i))
+		}
+		readings[i] = telemetry
+	}
+
+	return uplink.Batch{ID: data.ID, Token: data.Token, Readings: readings}, nil
+}
+
+func (t msgpackTelemetry) telemetry() (uplink.Telemetry, error) {
+	measurements, err := uplink.MeasurementsFromMap(t.Measurements)
+	if err != nil {
+		return uplink.Telemetry{}, erax.Wrap(err, "failed to decode msgpack measurements")
+	}
+
+	return uplink.Telemetry{
+		ID:           t.ID,
+		Token:        t.Token,
+		Latitude:     t.Latitude,
+		Longitude:    t.Longitude,
+		Altitude:     t.Altitude,
+		Battery:      t.Battery,
+		Timestamp:    t.Timestamp,
+		Seq:          t.Seq,
+		Measurements: measurements,
+	}, nil
+}
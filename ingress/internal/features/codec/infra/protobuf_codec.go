@@ -0,0 +1,80 @@
+package infra
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/DangeL187/erax"
+	"google.golang.org/protobuf/proto"
+
+	telemetryProto "ingress/internal/infra/proto/telemetry"
+	"ingress/internal/shared/uplink"
+)
+
+type ProtobufCodec struct{}
+
+func (ProtobufCodec) Name() string {
+	return "proto"
+}
+
+func (ProtobufCodec) ContentTypes() []string {
+	return []string{"application/x-protobuf", "application/protobuf", "application/vnd.google.protobuf"}
+}
+
+func (ProtobufCodec) Decode(payload []byte) (uplink.Telemetry, error) {
+	var data telemetryProto.Telemetry
+	if err := proto.Unmarshal(payload, &data); err != nil {
+		return uplink.Telemetry{}, erax.Wrap(err, "failed to unmarshal protobuf payload")
+	}
+
+	return telemetryFromProto(&data)
+}
+
+func (ProtobufCodec) DecodeBatch(payload []byte) (uplink.Batch, error) {
+	var data telemetryProto.TelemetryBatch
+	if err := proto.Unmarshal(payload, &data); err != nil {
+		return uplink.Batch{}, erax.Wrap(err, "failed to unmarshal protobuf batch")
+	}
+
+	readings := make([]uplink.Telemetry, len(data.GetReadings()))
+	for i, reading := range data.GetReadings() {
+		telemetry, err := telemetryFromProto(reading)
+		if err != nil {
+			return uplink.Batch{}, erax.Wrap(err, "failed to decode protobuf batch reading "+strconv.Itoa(i))
+		}
+		readings[i] = telemetry
+	}
+
+	return uplink.Batch{ID: data.GetId(), Token: data.GetToken(), Readings: readings}, nil
+}
+
+func telemetryFromProto(t *telemetryProto.Telemetry) (uplink.Telemetry, error) {
+	var measurements map[string]uplink.Measurement
+	if len(t.GetMeasurements()) > 0 {
+		measurements = make(map[string]uplink.Measurement, len(t.GetMeasurements()))
+	}
+	for name, measurement := range t.GetMeasurements() {
+		switch value := measurement.GetValue().(type) {
+		case *telemetryProto.Measurement_Number:
+			measurements[name] = uplink.NumberMeasurement(value.Number)
+		case *telemetryProto.Measurement_Bool:
+			measurements[name] = uplink.BoolMeasurement(value.Bool)
+		case *telemetryProto.Measurement_String_:
+			measurements[name] = uplink.StringMeasurement(value.String_)
+		default:
+			return uplink.Telemetry{}, fmt.Errorf("measurement %q has no value", name)
+		}
+	}
+
+	return uplink.Telemetry{
+		ID:           t.GetId(),
+		Token:        t.GetToken(),
+		Latitude:     t.GetLatitude(),
+		Longitude:    t.GetLongitude(),
+		Altitude:     t.GetAltitude(),
+		Battery:      t.GetBattery(),
+		Timestamp:    t.GetTimestamp(),
+		Seq:          t.GetSeq(),
+		Measurements: measurements,
+	}, nil
+}
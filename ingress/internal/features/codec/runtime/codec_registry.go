@@ -0,0 +1,130 @@
+package runtime
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+
+	"github.com/DangeL187/erax"
+
+	"ingress/internal/infra/metrics"
+	"ingress/internal/shared/uplink"
+)
+
+type codec interface {
+	Name() string
+	ContentTypes() []string
+	Decode(payload []byte) (uplink.Telemetry, error)
+	DecodeBatch(payload []byte) (uplink.Batch, error)
+}
+
+// CodecRegistry selects a codec for each uplink: by content type when the transport carries one (MQTT v5),
+// otherwise by the last topic level (e.g. devices/telemetry/cbor), falling back to the default codec.
+type CodecRegistry struct {
+	byName        map[string]codec
+	byContentType map[string]codec
+	fallback      codec
+
+	batchMaxSize  int
+	batchMaxBytes int
+}
+
+// Decode returns the decoded record and the name of the codec that decoded it, or what the consumer already decoded
+// when it set msg.Decoded.
+func (cr *CodecRegistry) Decode(msg *uplink.Message) (uplink.Telemetry, string, error) {
+	if msg.Decoded != nil {
+		return msg.Decoded.Telemetry, msg.Decoded.Codec, nil
+	}
+
+	c, err := cr.codecFor(msg)
+	if err != nil {
+		return uplink.Telemetry{}, "", err
+	}
+
+	telemetry, err := c.Decode(msg.Payload)
+	if err != nil {
+		return uplink.Telemetry{}, c.Name(), erax.Wrap(err, "failed to decode payload")
+	}
+	metrics.MessagesDecoded.WithLabelValues(c.Name()).Inc()
+
+	return telemetry, c.Name(), nil
+}
+
+// DecodeBatch decompresses and decodes a batch envelope, returning the name of the codec that decoded it and the
+// compression it was sent with. Envelopes that decompress to more than batchMaxBytes fail with uplink.ErrBatchTooLarge
+// before they are decoded; those with more than batchMaxSize readings fail with it too, but still return their
+// envelope for the rejection. Like Decode, it returns what the consumer already decoded when it set msg.Decoded.
+func (cr *CodecRegistry) DecodeBatch(msg *uplink.Message) (uplink.Batch, string, string, error) {
+	if msg.Decoded != nil {
+		return msg.Decoded.Batch, msg.Decoded.Codec, msg.Decoded.Encoding, nil
+	}
+
+	c, err := cr.codecFor(msg)
+	if err != nil {
+		return uplink.Batch{}, "", "", err
+	}
+
+	payload, encoding, err := decompress(msg.Payload, cr.batchMaxBytes)
+	if err != nil {
+		return uplink.Batch{}, c.Name(), encoding, err
+	}
+
+	batch, err := c.DecodeBatch(payload)
+	if err != nil {
+		return uplink.Batch{}, c.Name(), encoding, erax.Wrap(err, "failed to decode batch")
+	}
+	if len(batch.Readings) > cr.batchMaxSize {
+		return uplink.Batch{ID: batch.ID}, c.Name(), encoding,
+			fmt.Errorf("%w: %d, at most %d", uplink.ErrBatchTooLarge, len(batch.Readings), cr.batchMaxSize)
+	}
+	metrics.MessagesDecoded.WithLabelValues(c.Name()).Inc()
+
+	return batch, c.Name(), encoding, nil
+}
+
+// Decompress returns a batch envelope's payload uncompressed, e.g. so that tokens can be redacted from a rejected
+// batch before it is quarantined.
+func (cr *CodecRegistry) Decompress(payload []byte) ([]byte, error) {
+	out, _, err := decompress(payload, maxDecompressedSize)
+	return out, err
+}
+
+func (cr *CodecRegistry) codecFor(msg *uplink.Message) (codec, error) {
+	if msg.ContentType != "" {
+		mediaType, _, err := mime.ParseMediaType(msg.ContentType)
+		if err != nil {
+			return nil, erax.Wrap(err, "failed to parse content type")
+		}
+		c, ok := cr.byContentType[mediaType]
+		if !ok {
+			return nil, fmt.Errorf("unsupported content type: %s", mediaType)
+		}
+		return c, nil
+	}
+
+	suffix := msg.Topic[strings.LastIndexByte(msg.Topic, '/')+1:]
+	if c, ok := cr.byName[suffix]; ok {
+		return c, nil
+	}
+
+	return cr.fallback, nil
+}
+
+func NewCodecRegistry(batchMaxSize int, fallback codec, codecs ...codec) *CodecRegistry {
+	cr := &CodecRegistry{
+		byName:        make(map[string]codec),
+		byContentType: make(map[string]codec),
+		fallback:      fallback,
+		batchMaxSize:  batchMaxSize,
+		batchMaxBytes: min(batchMaxSize*maxReadingBytes, maxDecompressedSize),
+	}
+
+	for _, c := range append([]codec{fallback}, codecs...) {
+		cr.byName[c.Name()] = c
+		for _, contentType := range c.ContentTypes() {
+			cr.byContentType[contentType] = c
+		}
+	}
+
+	return cr
+}
@@ -0,0 +1,85 @@
+package runtime
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/DangeL187/erax"
+	"github.com/klauspost/compress/zstd"
+
+	"ingress/internal/shared/uplink"
+)
+
+// maxDecompressedSize bounds what a compressed batch may expand to, so that a small payload cannot exhaust memory.
+const maxDecompressedSize = 4 << 20
+
+// maxReadingBytes is the most a single batch reading may take once decompressed. Together with BATCH_MAX_SIZE it
+// bounds an envelope before it is decoded, so that an oversized batch is refused without decoding all its readings.
+const maxReadingBytes = 4 << 10
+
+// Compressions of a batch envelope. They are recognized by the magic number the payload starts with, as MQTT v3 has
+// no way to declare them and no codec's batch starts with either.
+const (
+	EncodingIdentity = "identity"
+	EncodingGzip     = "gzip"
+	EncodingZstd     = "zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// zstdDecoder is shared by all producer workers, as DecodeAll is safe for concurrent use. NewReader only fails on
+// invalid options.
+var zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0), zstd.WithDecoderMaxMemory(maxDecompressedSize))
+
+// decompress returns the payload uncompressed and the encoding it was compressed with. Payloads that are, or would
+// decompress to, more than limit bytes fail with uplink.ErrBatchTooLarge.
+func decompress(payload []byte, limit int) ([]byte, string, error) {
+	switch {
+	case bytes.HasPrefix(payload, gzipMagic):
+		reader, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, EncodingGzip, erax.Wrap(err, "failed to read gzip header")
+		}
+		defer reader.Close()
+
+		out, err := io.ReadAll(io.LimitReader(reader, int64(limit)+1))
+		if err != nil {
+			return nil, EncodingGzip, erax.Wrap(err, "failed to decompress gzip payload")
+		}
+		if len(out) > limit {
+			return nil, EncodingGzip, tooLarge(limit)
+		}
+		return out, EncodingGzip, nil
+	case bytes.HasPrefix(payload, zstdMagic):
+		var header zstd.Header
+		if err := header.Decode(payload); err != nil {
+			return nil, EncodingZstd, erax.Wrap(err, "failed to read zstd header")
+		}
+		if header.HasFCS && header.FrameContentSize > uint64(limit) {
+			return nil, EncodingZstd, tooLarge(limit)
+		}
+
+		out, err := zstdDecoder.DecodeAll(payload, nil)
+		if err != nil {
+			return nil, EncodingZstd, erax.Wrap(err, "failed to decompress zstd payload")
+		}
+		if len(out) > limit {
+			return nil, EncodingZstd, tooLarge(limit)
+		}
+		return out, EncodingZstd, nil
+	default:
+		if len(payload) > limit {
+			return nil, EncodingIdentity, tooLarge(limit)
+		}
+		return payload, EncodingIdentity, nil
+	}
+}
+
+func tooLarge(limit int) error {
+	return fmt.Errorf("%w: decompresses to more than %d bytes", uplink.ErrBatchTooLarge, limit)
+}
@@ -2,16 +2,18 @@ package runtime
 
 import (
 	"go.uber.org/zap"
+	"sync"
 	"time"
 
 	"github.com/DangeL187/erax"
 
 	"ingress/internal/infra/metrics"
 	"ingress/internal/shared/config"
+	"ingress/internal/shared/uplink"
 )
 
 type consumer interface {
-	Run(messageHandler func([]byte)) error
+	Run(messageHandler func(msg *uplink.Message) bool) error
 	Stop() error
 }
 
@@ -20,7 +22,12 @@ type ConsumerLoop struct {
 
 	consumer consumer
 
-	msgChanOut chan<- []byte
+	msgChanOut chan<- *uplink.Message
+
+	// handlers hold stopMu for reading while sending, so that Stop can wait for them before msgChanOut is closed
+	stopMu  sync.RWMutex
+	stopped bool
+	done    chan struct{}
 }
 
 func (cl *ConsumerLoop) Run() error {
@@ -33,32 +40,84 @@ func (cl *ConsumerLoop) Run() error {
 }
 
 func (cl *ConsumerLoop) Stop() {
+	close(cl.done)
+
 	err := cl.consumer.Stop()
 	if err != nil {
 		zap.L().Error("failed to stop consumer", zap.Error(err))
 	}
+
+	cl.stopMu.Lock()
+	cl.stopped = true
+	cl.stopMu.Unlock()
 }
 
-func (cl *ConsumerLoop) handleIncomingMessage(payload []byte) {
+// handleIncomingMessage reports whether the message was enqueued, so that consumers with acknowledgements
+// can leave rejected messages for redelivery.
+func (cl *ConsumerLoop) handleIncomingMessage(msg *uplink.Message) bool {
 	start := time.Now()
+	if msg.ReceivedAt.IsZero() {
+		msg.ReceivedAt = start
+	}
 	metrics.MessagesReceived.Inc()
+	defer func() {
+		metrics.ConsumerLatency.Observe(time.Since(start).Seconds())
+	}()
+
+	cl.stopMu.RLock()
+	defer cl.stopMu.RUnlock()
+
+	if cl.stopped {
+		return false
+	}
+
+	if cl.cfg.OverflowPolicy == config.OverflowPolicyDrop {
+		select {
+		case cl.msgChanOut <- msg:
+			return true
+		default:
+			metrics.MessagesDropped.Inc()
+			zap.L().Debug("msgChanOut full: dropping message")
+			return false
+		}
+	}
 
 	select {
-	case cl.msgChanOut <- payload:
+	case cl.msgChanOut <- msg:
+		return true
 	default:
-		metrics.MessagesDropped.Inc()
-		// TODO: ask for retry
-		zap.L().Debug("msgChanOut full: dropping message")
 	}
 
-	metrics.ConsumerLatency.Observe(time.Since(start).Seconds())
+	// the consumer leaves it unacknowledged and waits for redelivery off its receive path, which must not block
+	if cl.cfg.OverflowPolicy == config.OverflowPolicyAck {
+		metrics.MessagesUnacked.Inc()
+		zap.L().Debug("msgChanOut full: leaving message unacknowledged for redelivery")
+		return false
+	}
+
+	metrics.MessagesBackpressured.Inc()
+
+	timer := time.NewTimer(cl.cfg.OverflowTimeout)
+	defer timer.Stop()
+
+	select {
+	case cl.msgChanOut <- msg:
+		return true
+	case <-timer.C:
+	case <-cl.done:
+	}
+
+	metrics.MessagesDropped.Inc()
+	zap.L().Debug("msgChanOut full after waiting: dropping message")
+	return false
 }
 
-func NewConsumerLoop(cfg *config.Config, msgChanOut chan<- []byte, consumer consumer) (*ConsumerLoop, error) {
+func NewConsumerLoop(cfg *config.Config, msgChanOut chan<- *uplink.Message, consumer consumer) (*ConsumerLoop, error) {
 	cl := &ConsumerLoop{
 		cfg:        cfg,
 		consumer:   consumer,
 		msgChanOut: msgChanOut,
+		done:       make(chan struct{}),
 	}
 
 	return cl, nil
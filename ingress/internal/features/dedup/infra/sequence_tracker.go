@@ -0,0 +1,123 @@
+package infra
+
+import (
+	"math/bits"
+	"sync"
+
+	"ingress/internal/infra/metrics"
+)
+
+// sequenceWindow remembers which of the last len(seen)*64 sequence numbers up to max were received, in a ring
+// bitset indexed by seq modulo the window size.
+type sequenceWindow struct {
+	max  uint64
+	seen []uint64
+}
+
+func (w *sequenceWindow) size() uint64 {
+	return uint64(len(w.seen)) * 64
+}
+
+func (w *sequenceWindow) isSet(seq uint64) bool {
+	i := seq % w.size()
+	return w.seen[i/64]&(1<<(i%64)) != 0
+}
+
+func (w *sequenceWindow) set(seq uint64) {
+	i := seq % w.size()
+	w.seen[i/64] |= 1 << (i % 64)
+}
+
+func (w *sequenceWindow) clear(seq uint64) {
+	i := seq % w.size()
+	w.seen[i/64] &^= 1 << (i % 64)
+}
+
+// reset starts tracking at seq, treating everything before it as received.
+func (w *sequenceWindow) reset(seq uint64) {
+	w.max = seq
+	for i := range w.seen {
+		w.seen[i] = ^uint64(0)
+	}
+}
+
+// advance moves the window up to seq and returns how many sequence numbers left it without being received.
+func (w *sequenceWindow) advance(seq uint64) uint64 {
+	var lost uint64
+
+	if seq-w.max >= w.size() {
+		for _, word := range w.seen {
+			lost += uint64(64 - bits.OnesCount64(word))
+		}
+		lost += seq - w.max - w.size()
+		clear(w.seen)
+	} else {
+		for s := w.max + 1; s <= seq; s++ {
+			// the slot for s last held s - size, which is now leaving the window
+			if !w.isSet(s) {
+				lost++
+			}
+			w.clear(s)
+		}
+	}
+	w.max = seq
+
+	return lost
+}
+
+// SequenceTracker detects duplicate and missing per-device sequence numbers. Numbers are counted as lost only once
+// they slide out of the window unseen, so reordered messages are not reported as losses.
+type SequenceTracker struct {
+	mu         sync.Mutex
+	windows    map[string]*sequenceWindow
+	windowSize int
+	maxSize    int
+}
+
+// IsDuplicate records seq for the device and reports whether it was already received.
+func (st *SequenceTracker) IsDuplicate(deviceID string, seq uint64) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	w, ok := st.windows[deviceID]
+	if !ok {
+		if len(st.windows) >= st.maxSize {
+			// map iteration order is random, so this evicts an arbitrary device; its series goes with it, so that
+			// messages_lost_total never has more series than tracked devices
+			for k := range st.windows {
+				delete(st.windows, k)
+				metrics.MessagesLost.DeleteLabelValues(k)
+				break
+			}
+		}
+		w = &sequenceWindow{seen: make([]uint64, st.windowSize/64)}
+		w.reset(seq)
+		st.windows[deviceID] = w
+		return false
+	}
+
+	switch {
+	case seq > w.max:
+		if lost := w.advance(seq); lost > 0 {
+			metrics.MessagesLost.WithLabelValues(deviceID).Add(float64(lost))
+		}
+	case w.max-seq >= w.size():
+		// far behind the window: the device has restarted its counter
+		metrics.SequenceResets.Inc()
+		w.reset(seq)
+		return false
+	case w.isSet(seq):
+		return true
+	}
+	w.set(seq)
+
+	return false
+}
+
+func NewSequenceTracker(windowSize, maxSize int) *SequenceTracker {
+	return &SequenceTracker{
+		windows:    make(map[string]*sequenceWindow),
+		windowSize: windowSize,
+		maxSize:    maxSize,
+	}
+}
@@ -0,0 +1,45 @@
+package domain
+
+// Headers attached to messages moved to the dead-letter topic.
+const (
+	HeaderDLQOriginalTopic = "dlq_original_topic"
+	HeaderDLQReason        = "dlq_reason"
+	HeaderDLQAttempts      = "dlq_attempts"
+	HeaderDLQFailedAt      = "dlq_failed_at"
+)
+
+// Headers attached to every message sent to the main topic.
+const (
+	HeaderIngestedAt          = "ingested_at" // unix milliseconds
+	HeaderIngressInstance     = "ingress_instance"
+	HeaderMQTTTopic           = "mqtt_topic"
+	HeaderSchemaVersion       = "schema_version"
+	HeaderDeviceSchemaVersion = "device_schema_version" // only when the device sent one
+	HeaderCodec               = "codec"
+	HeaderTraceParent         = "traceparent"
+)
+
+type Header struct {
+	Key   string
+	Value string
+}
+
+type Message struct {
+	Topic   string
+	Key     string // device ID, so that one device's messages stay ordered within a partition
+	Payload []byte
+	Headers []Header
+}
+
+type SendError struct {
+	Message *Message
+	Err     error
+}
+
+func (e *SendError) Error() string {
+	return "failed to send message to " + e.Message.Topic + ": " + e.Err.Error()
+}
+
+func (e *SendError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,30 @@
+package domain
+
+// Reasons a message is rejected by the ProducerLoop and sent to the quarantine topic.
+const (
+	RejectDecodeError      = "decode_error"
+	RejectAuthFailed       = "auth_failed"
+	RejectValidationFailed = "validation_failed"
+)
+
+// Headers attached to messages sent to the quarantine topic.
+const (
+	HeaderQuarantineReason   = "quarantine_reason"
+	HeaderQuarantineError    = "quarantine_error"
+	HeaderQuarantineDeviceID = "quarantine_device_id"
+	HeaderQuarantinedAt      = "quarantined_at"
+)
+
+type RejectError struct {
+	Reason   string
+	DeviceID string // empty when the payload could not be decoded
+	Err      error
+}
+
+func (e *RejectError) Error() string {
+	return e.Reason + ": " + e.Err.Error()
+}
+
+func (e *RejectError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,49 @@
+package infra
+
+import (
+	"hash/fnv"
+
+	"github.com/IBM/sarama"
+)
+
+// jumpHashPartitioner maps keys to partitions with jump consistent hashing (Lamping and Veach), so adding
+// partitions to a topic moves only about 1/n of the devices instead of nearly all of them. Messages without
+// a key fall back to a random partition.
+type jumpHashPartitioner struct {
+	random sarama.Partitioner
+}
+
+func (p *jumpHashPartitioner) Partition(message *sarama.ProducerMessage, numPartitions int32) (int32, error) {
+	if message.Key == nil {
+		return p.random.Partition(message, numPartitions)
+	}
+
+	key, err := message.Key.Encode()
+	if err != nil {
+		return -1, err
+	}
+
+	hasher := fnv.New64a()
+	_, _ = hasher.Write(key)
+
+	return jumpHash(hasher.Sum64(), numPartitions), nil
+}
+
+func (p *jumpHashPartitioner) RequiresConsistency() bool {
+	return true
+}
+
+func jumpHash(key uint64, numBuckets int32) int32 {
+	var b, j int64 = -1, 0
+	for j < int64(numBuckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+
+	return int32(b)
+}
+
+func newJumpHashPartitioner(topic string) sarama.Partitioner {
+	return &jumpHashPartitioner{random: sarama.NewRandomPartitioner(topic)}
+}
@@ -5,38 +5,61 @@ import (
 
 	"github.com/DangeL187/erax"
 	"github.com/IBM/sarama"
+
+	"ingress/internal/features/producer/domain"
+	"ingress/internal/shared/config"
 )
 
 type KafkaProducer struct {
 	producer sarama.AsyncProducer
-	errChan  chan error
+	errChan  chan *domain.SendError
+	done     chan struct{} // closed with errChan, once the producer has shut down
 }
 
-func (kp *KafkaProducer) Produce(topic string, payload []byte) {
-	kp.producer.Input() <- &sarama.ProducerMessage{
-		Topic: topic,
-		Value: sarama.ByteEncoder(payload),
+func (kp *KafkaProducer) Produce(msg *domain.Message) {
+	producerMessage := &sarama.ProducerMessage{
+		Topic:    msg.Topic,
+		Value:    sarama.ByteEncoder(msg.Payload),
+		Metadata: msg,
+	}
+	if msg.Key != "" {
+		producerMessage.Key = sarama.StringEncoder(msg.Key)
+	}
+
+	for _, header := range msg.Headers {
+		producerMessage.Headers = append(producerMessage.Headers, sarama.RecordHeader{
+			Key:   []byte(header.Key),
+			Value: []byte(header.Value),
+		})
 	}
+
+	kp.producer.Input() <- producerMessage
 }
 
+// Close flushes the buffered messages and returns once the producer has shut down. Errors keeps delivering their
+// failures meanwhile, so it must be read until it is closed.
 func (kp *KafkaProducer) Close() error {
-	err := kp.producer.Close()
-	if err != nil {
-		return erax.Wrap(err, "failed to close kafka producer")
-	}
+	kp.producer.AsyncClose()
+	<-kp.done
 
 	return nil
 }
 
-func (kp *KafkaProducer) Errors() <-chan error {
+func (kp *KafkaProducer) Errors() <-chan *domain.SendError {
 	return kp.errChan
 }
 
-func NewKafkaProducer(kafkaBroker string) (*KafkaProducer, error) {
+func NewKafkaProducer(cfg *config.Config) (*KafkaProducer, error) {
 	kafkaConfig := sarama.NewConfig()
-	kafkaConfig.Producer.RequiredAcks = sarama.WaitForLocal
-	kafkaConfig.Producer.Retry.Max = 3
-	kafkaConfig.Producer.Idempotent = false
+	// retries could reorder or duplicate a device's messages within its partition without the idempotent producer,
+	// which needs acks from all in-sync replicas and one in-flight request per broker
+	kafkaConfig.Producer.RequiredAcks = sarama.WaitForAll
+	kafkaConfig.Producer.Retry.Max = cfg.KafkaRetryMax
+	kafkaConfig.Producer.Retry.BackoffFunc = func(retries, _ int) time.Duration {
+		return cfg.KafkaRetryBackoff << (retries - 1) // retries starts at 1
+	}
+	kafkaConfig.Producer.Idempotent = true
+	kafkaConfig.Net.MaxOpenRequests = 1
 	kafkaConfig.Producer.Flush.Frequency = 5 * time.Millisecond
 	kafkaConfig.Producer.Flush.Bytes = 32 * 1024 // 32 KB
 	kafkaConfig.Producer.Compression = sarama.CompressionLZ4
@@ -44,20 +67,45 @@ func NewKafkaProducer(kafkaBroker string) (*KafkaProducer, error) {
 	kafkaConfig.Producer.Return.Successes = false
 	kafkaConfig.Producer.Return.Errors = true
 
-	producer, err := sarama.NewAsyncProducer([]string{kafkaBroker}, kafkaConfig)
+	switch cfg.KafkaPartitioner {
+	case config.PartitionerConsistentHash:
+		kafkaConfig.Producer.Partitioner = newJumpHashPartitioner
+	default:
+		kafkaConfig.Producer.Partitioner = sarama.NewHashPartitioner
+	}
+
+	tlsConfig, err := cfg.KafkaTLS.ClientTLS()
+	if err != nil {
+		return nil, erax.Wrap(err, "failed to load kafka tls config")
+	}
+	if tlsConfig != nil {
+		kafkaConfig.Net.TLS.Enable = true
+		kafkaConfig.Net.TLS.Config = tlsConfig
+	}
+
+	producer, err := sarama.NewAsyncProducer([]string{cfg.KafkaBroker}, kafkaConfig)
 	if err != nil {
 		return nil, erax.Wrap(err, "failed to create kafka producer")
 	}
 
 	kp := &KafkaProducer{
 		producer: producer,
-		errChan:  make(chan error, 100),
+		errChan:  make(chan *domain.SendError, 100),
+		done:     make(chan struct{}),
 	}
 
 	go func() {
+		defer close(kp.done)
 		defer close(kp.errChan)
-		for err = range kp.producer.Errors() {
-			kp.errChan <- err
+		for producerErr := range kp.producer.Errors() {
+			msg, ok := producerErr.Msg.Metadata.(*domain.Message)
+			if !ok {
+				continue
+			}
+			kp.errChan <- &domain.SendError{
+				Message: msg,
+				Err:     producerErr.Err,
+			}
 		}
 	}()
 
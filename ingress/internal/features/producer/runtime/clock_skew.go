@@ -0,0 +1,43 @@
+package runtime
+
+import (
+	"time"
+
+	"ingress/internal/infra/metrics"
+	"ingress/internal/shared/config"
+	"ingress/internal/shared/uplink"
+)
+
+const (
+	skewAhead  = "ahead"
+	skewBehind = "behind"
+)
+
+// checkClockSkew records the difference between the device clock and receivedAt and reports whether it
+// exceeds CLOCK_SKEW_WINDOW. With the clamp policy, such readings get their timestamp moved to the edge of the
+// window, so that a device with a broken clock cannot write into arbitrary ClickHouse partitions.
+func (ps *ProducerLoop) checkClockSkew(data *uplink.Telemetry, receivedAt time.Time) bool {
+	skew := receivedAt.Sub(time.UnixMilli(data.Timestamp))
+	// separate series, so that clocks running ahead and late or buffered readings do not average each other out
+	direction := skewBehind
+	if skew < 0 {
+		direction = skewAhead
+	}
+	metrics.ClockSkew.WithLabelValues(direction).Observe(skew.Abs().Seconds())
+
+	window := ps.cfg.ClockSkewWindow
+	if skew.Abs() <= window {
+		return false
+	}
+	metrics.ClockSkewed.WithLabelValues(direction).Inc()
+
+	if ps.cfg.ClockSkewPolicy == config.ClockSkewPolicyClamp {
+		if skew > 0 {
+			data.Timestamp = receivedAt.Add(-window).UnixMilli()
+		} else {
+			data.Timestamp = receivedAt.Add(window).UnixMilli()
+		}
+	}
+
+	return true
+}
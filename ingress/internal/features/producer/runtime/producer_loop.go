@@ -4,79 +4,139 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"go.uber.org/zap"
+	"math"
+	"regexp"
 	"runtime"
+	"slices"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/DangeL187/erax"
 
+	"ingress/internal/features/producer/domain"
 	"ingress/internal/infra/metrics"
+	"ingress/internal/shared/auth"
+	"ingress/internal/shared/config"
+	"ingress/internal/shared/trace"
+	"ingress/internal/shared/uplink"
 )
 
 type producer interface {
-	Produce(topic string, payload []byte)
+	Produce(msg *domain.Message)
 	Close() error
-	Errors() <-chan error
+	Errors() <-chan *domain.SendError
+}
+
+type decoder interface {
+	Decode(msg *uplink.Message) (uplink.Telemetry, string, error)
+	DecodeBatch(msg *uplink.Message) (uplink.Batch, string, string, error)
+	Decompress(payload []byte) ([]byte, error)
+}
+
+type sequenceTracker interface {
+	IsDuplicate(deviceID string, seq uint64) bool
 }
 
 type authService interface {
 	Run(ctx context.Context)
 	Stop()
-	Auth(deviceID, deviceToken string) error
+	Auth(deviceID, deviceToken string, reply uplink.Reply) error
+	Match(verifiedID, verifiedToken, deviceID, deviceToken string, reply uplink.Reply) error
+}
+
+type rateLimiter interface {
+	Run(ctx context.Context)
+	Stop()
+	Allow(deviceID string, reply uplink.Reply) bool
 }
 
 type ProducerLoop struct {
-	msgChanIn <-chan []byte
+	cfg       *config.Config
+	msgChanIn <-chan *uplink.Message
 
-	authService authService
-	producer    producer
+	authService     authService
+	decoder         decoder
+	producer        producer
+	rateLimiter     rateLimiter
+	router          *router
+	sequenceTracker sequenceTracker
 
 	bufPool *sync.Pool
 
-	drainWg sync.WaitGroup
-	sendWg  sync.WaitGroup
+	// deadLetters feeds the dead-letter worker; drain workers hold deadLettersMu for reading while sending, so that
+	// Stop can close it before the producer
+	deadLetters       chan *domain.Message
+	deadLettersMu     sync.RWMutex
+	deadLettersClosed bool
+
+	deadLetterWg sync.WaitGroup
+	drainWg      sync.WaitGroup
+	sendWg       sync.WaitGroup
 }
 
-func (ps *ProducerLoop) Run(ctx context.Context, kafkaTopic string) {
+// deadLetterQueueSize bounds the messages waiting for the dead-letter topic, e.g. while Kafka is unreachable.
+const deadLetterQueueSize = 1000
+
+func (ps *ProducerLoop) Run(ctx context.Context) {
 	ps.authService.Run(ctx)
-	ps.runDrainWorkers(ctx, 1)
-	ps.runProducerWorkers(ctx, runtime.NumCPU()*2, kafkaTopic)
+	ps.rateLimiter.Run(ctx)
+	ps.runDrainWorkers(1)
+	ps.runDeadLetterWorker()
+	ps.runProducerWorkers(ctx, runtime.NumCPU()*2)
 }
 
 func (ps *ProducerLoop) Stop() {
 	ps.sendWg.Wait()
+
+	// the producer cannot take dead letters once it is closing, so failures from here on are only counted
+	ps.deadLettersMu.Lock()
+	ps.deadLettersClosed = true
+	close(ps.deadLetters)
+	ps.deadLettersMu.Unlock()
+	ps.deadLetterWg.Wait()
+
+	// the drain workers keep reading errors until Close has flushed the producer and closed its error channel
 	if err := ps.producer.Close(); err != nil {
 		zap.L().Error("failed to close producer", zap.Error(err))
 	}
 	ps.drainWg.Wait()
 
 	ps.authService.Stop()
+	ps.rateLimiter.Stop()
 }
 
-func (ps *ProducerLoop) runDrainWorkers(ctx context.Context, workerCount int) {
+// runDrainWorkers reads producer errors until the producer is closed, as sarama stalls when they are not read.
+func (ps *ProducerLoop) runDrainWorkers(workerCount int) {
 	ps.drainWg.Add(workerCount)
 
 	for i := 0; i < workerCount; i++ {
 		go func() {
 			defer ps.drainWg.Done()
-			for {
-				select {
-				case err, ok := <-ps.producer.Errors():
-					if !ok {
-						return
-					}
-					metrics.MessagesSendErrors.Inc()
-					// TODO: DLQ
-					zap.L().Error("Kafka send error", zap.Error(err))
-				case <-ctx.Done():
-					return
-				}
+			for sendErr := range ps.producer.Errors() {
+				metrics.MessagesSendErrors.Inc()
+				ps.handleSendError(sendErr)
 			}
 		}()
 	}
 }
 
-func (ps *ProducerLoop) runProducerWorkers(ctx context.Context, workerCount int, kafkaTopic string) {
+// runDeadLetterWorker produces dead letters in the order they failed, off the drain workers.
+func (ps *ProducerLoop) runDeadLetterWorker() {
+	ps.deadLetterWg.Add(1)
+
+	go func() {
+		defer ps.deadLetterWg.Done()
+		for msg := range ps.deadLetters {
+			ps.producer.Produce(msg)
+		}
+	}()
+}
+
+func (ps *ProducerLoop) runProducerWorkers(ctx context.Context, workerCount int) {
 	ps.sendWg.Add(workerCount)
 
 	for i := 0; i < workerCount; i++ {
@@ -84,16 +144,19 @@ func (ps *ProducerLoop) runProducerWorkers(ctx context.Context, workerCount int,
 			defer ps.sendWg.Done()
 			for {
 				select {
-				case payload, ok := <-ps.msgChanIn:
+				case msg, ok := <-ps.msgChanIn:
 					if !ok {
 						return
 					}
-					processedPayload, err := ps.processMessage(payload)
+					records, err := ps.processMessage(msg)
 					if err != nil {
-						zap.S().Errorf("failed to process message:\n%f", err)
+						ps.reject(ps.quarantinePayload(msg), err)
+						continue
+					}
+					for _, record := range records {
+						ps.producer.Produce(record)
+						metrics.MessagesSent.Inc()
 					}
-					ps.producer.Produce(kafkaTopic, processedPayload)
-					metrics.MessagesSent.Inc()
 				case <-ctx.Done():
 					return
 				}
@@ -102,38 +165,284 @@ func (ps *ProducerLoop) runProducerWorkers(ctx context.Context, workerCount int,
 	}
 }
 
-type deviceData struct {
-	ID        string  `json:"id"`
-	Latitude  float64 `json:"latitude"`
-	Longitude float64 `json:"longitude"`
-	Altitude  float64 `json:"altitude"`
-	Battery   float64 `json:"battery"`
-	Timestamp int64   `json:"timestamp"`
-	Token     string  `json:"token"`
+// quarantinePayload returns the payload of a rejected message in a form redactTokens can see into: batch envelopes
+// are decompressed first, and dropped when that fails, so that a compressed token never reaches the quarantine topic.
+func (ps *ProducerLoop) quarantinePayload(msg *uplink.Message) []byte {
+	if !uplink.IsBatchTopic(msg.Topic) {
+		return msg.Payload
+	}
+
+	payload, err := ps.decoder.Decompress(msg.Payload)
+	if err != nil {
+		return nil
+	}
+
+	return payload
+}
+
+// reject sends a message that failed processMessage to the quarantine topic
+// instead of the main one, so malformed telemetry never reaches ClickHouse.
+func (ps *ProducerLoop) reject(payload []byte, err error) {
+	var rejectErr *domain.RejectError
+	if !errors.As(err, &rejectErr) {
+		zap.S().Errorf("failed to process message:\n%f", err)
+		return
+	}
+
+	metrics.MessagesRejected.WithLabelValues(rejectErr.Reason).Inc()
+	zap.S().Debugf("rejected message:\n%f", err)
+
+	if ps.cfg.KafkaQuarantineTopic == "" {
+		return
+	}
+	ps.producer.Produce(quarantineMessage(ps.cfg.KafkaQuarantineTopic, payload, rejectErr))
+}
+
+// handleSendError moves a message that sarama failed to send, after its KAFKA_RETRY_MAX retries, to the
+// dead-letter topic.
+func (ps *ProducerLoop) handleSendError(sendErr *domain.SendError) {
+	msg := sendErr.Message
+
+	if ps.cfg.KafkaDLQTopic != "" && msg.Topic == ps.cfg.KafkaDLQTopic {
+		metrics.DeadLetterErrors.Inc()
+		zap.L().Error("failed to send message to dead-letter topic", zap.Error(sendErr))
+		return
+	}
+
+	if ps.cfg.KafkaDLQTopic == "" {
+		zap.L().Error("Kafka send error, giving up", zap.Error(sendErr))
+		return
+	}
+
+	ps.deadLetter(&domain.Message{
+		Topic:   ps.cfg.KafkaDLQTopic,
+		Key:     msg.Key,
+		Payload: msg.Payload,
+		Headers: append(slices.Clone(msg.Headers),
+			domain.Header{Key: domain.HeaderDLQOriginalTopic, Value: msg.Topic},
+			domain.Header{Key: domain.HeaderDLQReason, Value: sendErr.Err.Error()},
+			domain.Header{Key: domain.HeaderDLQAttempts, Value: strconv.Itoa(ps.cfg.KafkaRetryMax + 1)},
+			domain.Header{Key: domain.HeaderDLQFailedAt, Value: time.Now().UTC().Format(time.RFC3339Nano)},
+		),
+	}, sendErr)
+}
+
+// deadLetter queues msg for the dead-letter worker without blocking the drain worker, which must keep reading
+// producer errors for sarama to make progress. msg is lost when the queue is full or the loop is stopping.
+func (ps *ProducerLoop) deadLetter(msg *domain.Message, sendErr *domain.SendError) {
+	ps.deadLettersMu.RLock()
+	defer ps.deadLettersMu.RUnlock()
+
+	if !ps.deadLettersClosed {
+		select {
+		case ps.deadLetters <- msg:
+			zap.L().Warn("moving message to dead-letter topic", zap.Error(sendErr))
+			metrics.MessagesDeadLettered.Inc()
+			return
+		default:
+		}
+	}
+
+	metrics.DeadLetterErrors.Inc()
+	zap.L().Error("dead-letter queue is full or closed, giving up", zap.Error(sendErr))
 }
 
+const maxMeasurements = 64
+
+// measurementNamePattern keeps measurement names usable as ClickHouse map keys and Prometheus-style labels.
+var measurementNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_.]{0,63}$`)
+
+func validateTelemetry(d *uplink.Telemetry) error {
+	switch {
+	case math.IsNaN(d.Latitude) || d.Latitude < -90 || d.Latitude > 90:
+		return fmt.Errorf("latitude out of range: %v", d.Latitude)
+	case math.IsNaN(d.Longitude) || d.Longitude < -180 || d.Longitude > 180:
+		return fmt.Errorf("longitude out of range: %v", d.Longitude)
+	case math.IsNaN(d.Altitude) || math.IsInf(d.Altitude, 0):
+		return fmt.Errorf("altitude is not finite: %v", d.Altitude)
+	case math.IsNaN(d.Battery) || d.Battery < 0 || d.Battery > 100:
+		return fmt.Errorf("battery out of range: %v", d.Battery)
+	case d.Timestamp <= 0:
+		return fmt.Errorf("timestamp is not set: %d", d.Timestamp)
+	case len(d.Measurements) > maxMeasurements:
+		return fmt.Errorf("too many measurements: %d", len(d.Measurements))
+	}
+
+	for name, measurement := range d.Measurements {
+		if !measurementNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid measurement name: %q", name)
+		}
+		if !measurement.IsFinite() {
+			return fmt.Errorf("measurement %q is not finite", name)
+		}
+	}
+
+	return nil
+}
+
+// schemaVersion identifies the layout of kafkaDeviceData for consumers; bump it on incompatible changes.
+const schemaVersion = "2" // 2: timestamp in unix milliseconds instead of seconds
+
 type kafkaDeviceData struct {
 	ID        string  `json:"id"`
 	Latitude  float64 `json:"latitude"`
 	Longitude float64 `json:"longitude"`
 	Altitude  float64 `json:"altitude"`
 	Battery   float64 `json:"battery"`
-	Timestamp int64   `json:"timestamp"`
+	Timestamp int64   `json:"timestamp"` // unix milliseconds, device clock
+	Seq       uint64  `json:"seq,omitempty"`
+
+	ReceivedAt  int64 `json:"received_at"` // unix milliseconds, ingress clock
+	ClockSkewed bool  `json:"clock_skewed"`
+
+	Measurements map[string]uplink.Measurement `json:"measurements,omitempty"`
+}
+
+// processMessage returns the Kafka records of an uplink: one for a single reading, one per reading for a batch
+// envelope. Duplicates and throttled readings are dropped without quarantining them.
+func (ps *ProducerLoop) processMessage(msg *uplink.Message) ([]*domain.Message, error) {
+	if uplink.IsBatchTopic(msg.Topic) {
+		return ps.processBatch(msg)
+	}
+
+	data, codecName, err := ps.decoder.Decode(msg)
+	if err != nil {
+		return nil, &domain.RejectError{
+			Reason: domain.RejectDecodeError,
+			Err:    erax.Wrap(err, "failed to decode message payload"),
+		}
+	}
+
+	if data.Token == "" {
+		data.Token = msg.Token
+	}
+
+	if err = ps.authenticate(msg, data.ID, data.Token, withCorrelation(msg.Reply, data.Seq)); err != nil {
+		return nil, err
+	}
+
+	record, err := ps.processReading(msg, codecName, &data, msg.Admitted > 0)
+	if err != nil || record == nil {
+		return nil, err
+	}
+
+	return []*domain.Message{record}, nil
 }
 
-func (ps *ProducerLoop) processMessage(payload []byte) ([]byte, error) {
-	var data deviceData
-	if err := json.Unmarshal(payload, &data); err != nil {
-		return nil, erax.Wrap(err, "failed to unmarshal message payload")
+// processBatch authenticates a batch envelope once and turns each of its readings into a record of its own. Readings
+// that are rejected are quarantined one by one, so that they do not take the rest of the batch with them.
+func (ps *ProducerLoop) processBatch(msg *uplink.Message) ([]*domain.Message, error) {
+	batch, codecName, encoding, err := ps.decoder.DecodeBatch(msg)
+	if encoding != "" {
+		metrics.BatchBytes.WithLabelValues(encoding).Observe(float64(len(msg.Payload)))
+	}
+	if errors.Is(err, uplink.ErrBatchTooLarge) {
+		return nil, &domain.RejectError{
+			Reason:   domain.RejectValidationFailed,
+			DeviceID: batch.ID,
+			Err:      erax.Wrap(err, "failed to decode batch payload"),
+		}
+	}
+	if err != nil {
+		return nil, &domain.RejectError{
+			Reason: domain.RejectDecodeError,
+			Err:    erax.Wrap(err, "failed to decode batch payload"),
+		}
+	}
+	metrics.BatchReadings.Observe(float64(len(batch.Readings)))
+
+	if batch.Token == "" {
+		batch.Token = msg.Token
+	}
+
+	if err = ps.authenticate(msg, batch.ID, batch.Token, msg.Reply); err != nil {
+		return nil, err
+	}
+
+	records := make([]*domain.Message, 0, len(batch.Readings))
+	for i := range batch.Readings {
+		data := &batch.Readings[i]
+		if data.ID == "" {
+			data.ID = batch.ID
+		}
+		data.Token = ""
+
+		var record *domain.Message
+		if data.ID != batch.ID {
+			metrics.IdentityMismatch.Inc()
+			err = &domain.RejectError{
+				Reason:   domain.RejectAuthFailed,
+				DeviceID: batch.ID,
+				Err:      erax.Wrap(auth.ErrIdentityMismatch, "batch reading "+strconv.Itoa(i)+" is for another device"),
+			}
+		} else {
+			record, err = ps.processReading(msg, codecName, data, i < msg.Admitted)
+		}
+		if err != nil {
+			ps.reject(readingPayload(data), err)
+			continue
+		}
+		if record != nil {
+			records = append(records, record)
+		}
 	}
 
-	err := ps.authService.Auth(data.ID, data.Token)
+	return records, nil
+}
+
+// authenticate checks that deviceToken belongs to deviceID. Messages that their consumer already authenticated (e.g.
+// over a gRPC stream) are only bound to the verified device instead, so that neither a payload ID nor a payload token
+// can stand in for another device.
+func (ps *ProducerLoop) authenticate(msg *uplink.Message, deviceID, deviceToken string, reply uplink.Reply) error {
+	var err error
+	if msg.DeviceID != "" {
+		err = ps.authService.Match(msg.DeviceID, msg.Token, deviceID, deviceToken, reply)
+	} else {
+		err = ps.authService.Auth(deviceID, deviceToken, reply)
+	}
 	if err != nil {
-		metrics.AuthFail.Inc()
-		return nil, erax.Wrap(err, "failed to authenticate")
+		if errors.Is(err, auth.ErrIdentityMismatch) {
+			metrics.IdentityMismatch.Inc()
+		} else {
+			metrics.AuthFail.Inc()
+		}
+		return &domain.RejectError{
+			Reason:   domain.RejectAuthFailed,
+			DeviceID: deviceID,
+			Err:      erax.Wrap(err, "failed to authenticate"),
+		}
 	}
 	metrics.AuthSuccess.Inc()
 
+	return nil
+}
+
+// processReading turns an authenticated reading into its Kafka record, returning a nil record without an error for
+// duplicates and throttled readings. Readings that their consumer admitted are not charged to the rate limit again.
+func (ps *ProducerLoop) processReading(msg *uplink.Message, codecName string, data *uplink.Telemetry,
+	admitted bool) (*domain.Message, error) {
+	// after authentication, so that a device cannot spend another device's tokens
+	if !admitted && !ps.rateLimiter.Allow(data.ID, withCorrelation(msg.Reply, data.Seq)) {
+		return nil, nil
+	}
+
+	if err := validateTelemetry(data); err != nil {
+		return nil, &domain.RejectError{
+			Reason:   domain.RejectValidationFailed,
+			DeviceID: data.ID,
+			Err:      erax.Wrap(err, "invalid telemetry"),
+		}
+	}
+
+	// after authentication and validation, so that forged or rejected messages cannot mark sequence numbers as received
+	if data.Seq != 0 && ps.sequenceTracker.IsDuplicate(data.ID, data.Seq) {
+		metrics.MessagesDuplicate.Inc()
+		return nil, nil
+	}
+
+	data.Timestamp = uplink.UnixMilli(data.Timestamp)
+	clockSkewed := ps.checkClockSkew(data, msg.ReceivedAt)
+
 	kafkaData := kafkaDeviceData{
 		ID:        data.ID,
 		Latitude:  data.Latitude,
@@ -141,12 +450,18 @@ func (ps *ProducerLoop) processMessage(payload []byte) ([]byte, error) {
 		Altitude:  data.Altitude,
 		Battery:   data.Battery,
 		Timestamp: data.Timestamp,
+		Seq:       data.Seq,
+
+		ReceivedAt:  msg.ReceivedAt.UnixMilli(),
+		ClockSkewed: clockSkewed,
+
+		Measurements: data.Measurements,
 	}
 
 	buf := ps.bufPool.Get().(*bytes.Buffer)
 	buf.Reset()
 	encoder := json.NewEncoder(buf)
-	if err = encoder.Encode(&kafkaData); err != nil {
+	if err := encoder.Encode(&kafkaData); err != nil {
 		ps.bufPool.Put(buf)
 		return nil, erax.Wrap(err, "failed to encode data")
 	}
@@ -155,14 +470,67 @@ func (ps *ProducerLoop) processMessage(payload []byte) ([]byte, error) {
 	copy(out, buf.Bytes())
 	ps.bufPool.Put(buf)
 
-	return out, nil
+	headers := []domain.Header{
+		{Key: domain.HeaderIngestedAt, Value: strconv.FormatInt(time.Now().UnixMilli(), 10)},
+		{Key: domain.HeaderIngressInstance, Value: ps.cfg.InstanceID},
+		{Key: domain.HeaderMQTTTopic, Value: msg.Topic},
+		{Key: domain.HeaderSchemaVersion, Value: schemaVersion},
+		{Key: domain.HeaderCodec, Value: codecName},
+		{Key: domain.HeaderTraceParent, Value: trace.ChildTraceParent(msg.TraceParent)},
+	}
+	if msg.SchemaVersion != "" {
+		headers = append(headers, domain.Header{Key: domain.HeaderDeviceSchemaVersion, Value: msg.SchemaVersion})
+	}
+
+	return &domain.Message{
+		Topic:   ps.router.route(msg, data),
+		Key:     data.ID,
+		Payload: out,
+		Headers: headers,
+	}, nil
+}
+
+// withCorrelation uses the reading's sequence number as correlation data when the device sent none (e.g. over MQTT
+// v3), so that error responses still identify the rejected message.
+func withCorrelation(reply uplink.Reply, seq uint64) uplink.Reply {
+	if len(reply.CorrelationData) == 0 && seq != 0 {
+		reply.CorrelationData = []byte(strconv.FormatUint(seq, 10))
+	}
+
+	return reply
+}
+
+// readingPayload encodes a batch reading on its own for the quarantine topic, as it has no payload of its own.
+func readingPayload(data *uplink.Telemetry) []byte {
+	payload, err := json.Marshal(&kafkaDeviceData{
+		ID:           data.ID,
+		Latitude:     data.Latitude,
+		Longitude:    data.Longitude,
+		Altitude:     data.Altitude,
+		Battery:      data.Battery,
+		Timestamp:    data.Timestamp,
+		Seq:          data.Seq,
+		Measurements: data.Measurements,
+	})
+	if err != nil {
+		return nil // unencodable measurements, the quarantine headers still say what was wrong
+	}
+
+	return payload
 }
 
-func NewProducerLoop(msgChanIn <-chan []byte, authService authService, producer producer) *ProducerLoop {
+func NewProducerLoop(cfg *config.Config, msgChanIn <-chan *uplink.Message, authService authService,
+	rateLimiter rateLimiter, decoder decoder, sequenceTracker sequenceTracker, producer producer) *ProducerLoop {
 	return &ProducerLoop{
-		msgChanIn:   msgChanIn,
-		authService: authService,
-		producer:    producer,
+		cfg:             cfg,
+		msgChanIn:       msgChanIn,
+		authService:     authService,
+		decoder:         decoder,
+		producer:        producer,
+		rateLimiter:     rateLimiter,
+		router:          newRouter(cfg.KafkaTopic, cfg.KafkaRoutes),
+		sequenceTracker: sequenceTracker,
+		deadLetters:     make(chan *domain.Message, deadLetterQueueSize),
 		bufPool: &sync.Pool{
 			New: func() interface{} {
 				b := bytes.NewBuffer(make([]byte, 0, 128)) // 128 bytes
@@ -0,0 +1,42 @@
+package runtime
+
+import (
+	"regexp"
+	"time"
+
+	"ingress/internal/features/producer/domain"
+)
+
+const redacted = "[REDACTED]"
+
+var (
+	// tokenFieldPattern matches the "token" field even in payloads that are not valid JSON.
+	tokenFieldPattern = regexp.MustCompile(`("token"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	// jwtPattern catches tokens that ended up outside the "token" field.
+	jwtPattern = regexp.MustCompile(`eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+)
+
+// redactTokens removes device tokens from a rejected payload so the
+// quarantine topic can be read without exposing credentials.
+func redactTokens(payload []byte) []byte {
+	payload = tokenFieldPattern.ReplaceAll(payload, []byte(`$1"`+redacted+`"`))
+	return jwtPattern.ReplaceAll(payload, []byte(redacted))
+}
+
+func quarantineMessage(topic string, payload []byte, rejectErr *domain.RejectError) *domain.Message {
+	headers := []domain.Header{
+		{Key: domain.HeaderQuarantineReason, Value: rejectErr.Reason},
+		{Key: domain.HeaderQuarantineError, Value: string(redactTokens([]byte(rejectErr.Err.Error())))},
+		{Key: domain.HeaderQuarantinedAt, Value: time.Now().UTC().Format(time.RFC3339Nano)},
+	}
+	if rejectErr.DeviceID != "" {
+		headers = append(headers, domain.Header{Key: domain.HeaderQuarantineDeviceID, Value: rejectErr.DeviceID})
+	}
+
+	return &domain.Message{
+		Topic:   topic,
+		Key:     rejectErr.DeviceID,
+		Payload: redactTokens(payload),
+		Headers: headers,
+	}
+}
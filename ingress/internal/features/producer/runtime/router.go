@@ -0,0 +1,80 @@
+package runtime
+
+import (
+	"path"
+	"strconv"
+
+	"ingress/internal/infra/metrics"
+	"ingress/internal/shared/config"
+	"ingress/internal/shared/uplink"
+)
+
+const defaultRoute = "default"
+
+// router picks the destination topic for a message: the first matching KAFKA_ROUTES rule, else KAFKA_TOPIC.
+type router struct {
+	rules        []config.RouteRule
+	defaultTopic string
+}
+
+func (r *router) route(msg *uplink.Message, data *uplink.Telemetry) string {
+	for i := range r.rules {
+		if matchesRule(&r.rules[i], msg, data) {
+			metrics.MessagesRouted.WithLabelValues(r.rules[i].Name).Inc()
+			return r.rules[i].Topic
+		}
+	}
+
+	metrics.MessagesRouted.WithLabelValues(defaultRoute).Inc()
+	return r.defaultTopic
+}
+
+func matchesRule(rule *config.RouteRule, msg *uplink.Message, data *uplink.Telemetry) bool {
+	if rule.DeviceID != "" {
+		if ok, _ := path.Match(rule.DeviceID, data.ID); !ok {
+			return false
+		}
+	}
+
+	if rule.MQTTTopic != "" {
+		if ok, _ := path.Match(rule.MQTTTopic, msg.Topic); !ok {
+			return false
+		}
+	}
+
+	if rule.Measurement != "" {
+		measurement, ok := data.Measurements[rule.Measurement]
+		if !ok {
+			return false
+		}
+		if rule.Equals != "" && measurementString(measurement) != rule.Equals {
+			return false
+		}
+	}
+
+	return true
+}
+
+func measurementString(m uplink.Measurement) string {
+	switch m.Kind {
+	case uplink.MeasurementNumber:
+		return strconv.FormatFloat(m.Number, 'f', -1, 64)
+	case uplink.MeasurementBool:
+		return strconv.FormatBool(m.Bool)
+	default:
+		return m.String
+	}
+}
+
+func newRouter(defaultTopic string, rules []config.RouteRule) *router {
+	// initialise per-route series so that routes without traffic are reported as 0
+	metrics.MessagesRouted.WithLabelValues(defaultRoute)
+	for _, rule := range rules {
+		metrics.MessagesRouted.WithLabelValues(rule.Name)
+	}
+
+	return &router{
+		rules:        rules,
+		defaultTopic: defaultTopic,
+	}
+}
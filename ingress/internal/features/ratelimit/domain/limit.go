@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+// Limit is a token bucket refilled at Rate tokens per second up to Burst tokens. A zero Rate means unlimited.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Override replaces the default limit of one device; nil fields keep the default.
+type Override struct {
+	Rate  *float64
+	Burst *int
+}
+
+// Decision is the outcome of charging messages to a device's bucket.
+type Decision struct {
+	Allowed    bool
+	Taken      int           // messages charged, when allowed
+	RetryAfter time.Duration // until the next token, when not allowed
+
+	// Notify is set on the first denied message after allowed ones, so the device hears about throttling once
+	// per episode instead of once per dropped message.
+	Notify bool
+}
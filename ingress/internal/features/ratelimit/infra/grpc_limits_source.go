@@ -0,0 +1,42 @@
+package infra
+
+import (
+	"context"
+	"google.golang.org/grpc"
+
+	"github.com/DangeL187/erax"
+
+	"ingress/internal/features/ratelimit/domain"
+	pb "ingress/internal/infra/grpc/proto/auth"
+)
+
+// GRPCLimitsSource fetches per-device rate limit overrides from the auth service.
+type GRPCLimitsSource struct {
+	grpcAuthClient pb.AuthServiceClient
+}
+
+func (s *GRPCLimitsSource) GetOverrides(ctx context.Context) (map[string]domain.Override, error) {
+	resp, err := s.grpcAuthClient.GetRateLimits(ctx, &pb.GetRateLimitsRequest{})
+	if err != nil {
+		return nil, erax.Wrap(err, "failed to get rate limits")
+	}
+
+	overrides := make(map[string]domain.Override, len(resp.Limits))
+	for _, l := range resp.Limits {
+		override := domain.Override{Rate: l.Rate}
+		if l.Burst != nil && *l.Burst > 0 {
+			burst := int(*l.Burst)
+			override.Burst = &burst
+		}
+		overrides[l.DeviceId] = override
+	}
+
+	return overrides, nil
+}
+
+// NewGRPCLimitsSource uses conn, which the caller owns and closes.
+func NewGRPCLimitsSource(conn *grpc.ClientConn) *GRPCLimitsSource {
+	return &GRPCLimitsSource{
+		grpcAuthClient: pb.NewAuthServiceClient(conn),
+	}
+}
@@ -0,0 +1,104 @@
+package infra
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"ingress/internal/features/ratelimit/domain"
+)
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	throttled bool
+}
+
+// TokenBucketLimiter keeps a token bucket per device ID. The number of buckets is bounded by maxSize; an evicted
+// device starts over with a full bucket.
+type TokenBucketLimiter struct {
+	mu           sync.Mutex
+	buckets      map[string]*bucket
+	maxSize      int
+	defaultLimit domain.Limit
+	overrides    map[string]domain.Limit
+}
+
+// AllowN charges up to n messages to the device's bucket, as many as it has tokens for. It is allowed as long as
+// one of them is.
+func (l *TokenBucketLimiter) AllowN(deviceID string, n int, now time.Time) domain.Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit := l.limitFor(deviceID)
+	if limit.Rate == 0 {
+		return domain.Decision{Allowed: true, Taken: n}
+	}
+
+	b, ok := l.buckets[deviceID]
+	if !ok {
+		if len(l.buckets) >= l.maxSize {
+			// map iteration order is random, so this evicts an arbitrary device
+			for k := range l.buckets {
+				delete(l.buckets, k)
+				break
+			}
+		}
+		b = &bucket{tokens: float64(limit.Burst), updatedAt: now}
+		l.buckets[deviceID] = b
+	} else if elapsed := now.Sub(b.updatedAt); elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed.Seconds()*limit.Rate)
+		b.updatedAt = now
+	}
+
+	if b.tokens >= 1 {
+		taken := min(n, int(b.tokens))
+		b.tokens -= float64(taken)
+		b.throttled = false
+		return domain.Decision{Allowed: true, Taken: taken}
+	}
+
+	decision := domain.Decision{
+		RetryAfter: time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second)),
+		Notify:     !b.throttled,
+	}
+	b.throttled = true
+
+	return decision
+}
+
+// SetOverrides replaces all per-device overrides. Existing buckets keep their tokens, capped by the new burst on
+// the next message.
+func (l *TokenBucketLimiter) SetOverrides(overrides map[string]domain.Override) {
+	limits := make(map[string]domain.Limit, len(overrides))
+	for deviceID, override := range overrides {
+		limit := l.defaultLimit
+		if override.Rate != nil {
+			limit.Rate = *override.Rate
+		}
+		if override.Burst != nil {
+			limit.Burst = *override.Burst
+		}
+		limits[deviceID] = limit
+	}
+
+	l.mu.Lock()
+	l.overrides = limits
+	l.mu.Unlock()
+}
+
+func (l *TokenBucketLimiter) limitFor(deviceID string) domain.Limit {
+	if limit, ok := l.overrides[deviceID]; ok {
+		return limit
+	}
+
+	return l.defaultLimit
+}
+
+func NewTokenBucketLimiter(defaultLimit domain.Limit, maxSize int) *TokenBucketLimiter {
+	return &TokenBucketLimiter{
+		buckets:      make(map[string]*bucket),
+		maxSize:      maxSize,
+		defaultLimit: defaultLimit,
+	}
+}
@@ -0,0 +1,184 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+
+	"ingress/internal/features/ratelimit/domain"
+	"ingress/internal/infra/metrics"
+	"ingress/internal/shared/auth"
+	"ingress/internal/shared/uplink"
+)
+
+type throttleNotice struct {
+	DeviceID      string
+	CorrelationID string
+	RetryAfter    time.Duration
+	Respond       func(resp auth.ErrorResponse) error
+}
+
+type limiter interface {
+	AllowN(deviceID string, n int, now time.Time) domain.Decision
+	SetOverrides(overrides map[string]domain.Override)
+}
+
+type limitsSource interface {
+	GetOverrides(ctx context.Context) (map[string]domain.Override, error)
+}
+
+type publisher interface {
+	Publish(topic string, payload any) error
+}
+
+// RateLimitService throttles devices with per-device token buckets, keeps their overrides in sync with the auth
+// service and tells throttled devices when to retry through devices/<id>/throttle.
+type RateLimitService struct {
+	noticeChan chan throttleNotice
+	noticeWg   sync.WaitGroup
+	refreshWg  sync.WaitGroup
+
+	refreshInterval time.Duration
+
+	limiter      limiter
+	limitsSource limitsSource
+	publisher    publisher
+}
+
+func (rs *RateLimitService) Run(ctx context.Context) {
+	rs.runRefresher(ctx)
+	rs.runNoticeWorkers(ctx, 1)
+}
+
+func (rs *RateLimitService) Stop() {
+	close(rs.noticeChan)
+
+	rs.noticeWg.Wait()
+	rs.refreshWg.Wait()
+}
+
+// Allow reports whether the device may send another message now. The throttle notice identifies the first message
+// refused by the correlation ID of its reply, and goes over the transport it came in on when the reply has Respond.
+func (rs *RateLimitService) Allow(deviceID string, reply uplink.Reply) bool {
+	decision := rs.limiter.AllowN(deviceID, 1, time.Now())
+	if decision.Allowed {
+		return true
+	}
+
+	metrics.MessagesThrottled.Inc()
+
+	if decision.Notify {
+		select {
+		case rs.noticeChan <- throttleNotice{
+			DeviceID:      deviceID,
+			CorrelationID: reply.CorrelationID(),
+			RetryAfter:    decision.RetryAfter,
+			Respond:       reply.Respond,
+		}:
+		default:
+			// a throttled device must not slow down the producer workers
+		}
+	}
+
+	return false
+}
+
+// Admit charges up to n readings of a message to the device before its consumer acknowledges it, and returns how many
+// it charged, in payload order. When it reports the message as not allowed, the consumer should refuse it and tell the
+// device to retry after the returned duration itself, so no throttle notice is sent.
+func (rs *RateLimitService) Admit(deviceID string, n int) (int, time.Duration, bool) {
+	decision := rs.limiter.AllowN(deviceID, n, time.Now())
+	if !decision.Allowed {
+		metrics.MessagesThrottled.Add(float64(n))
+		return 0, decision.RetryAfter, false
+	}
+
+	return decision.Taken, 0, true
+}
+
+func (rs *RateLimitService) runRefresher(ctx context.Context) {
+	rs.refreshWg.Add(1)
+
+	go func() {
+		defer rs.refreshWg.Done()
+
+		ticker := time.NewTicker(rs.refreshInterval)
+		defer ticker.Stop()
+
+		for {
+			rs.refresh(ctx)
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (rs *RateLimitService) refresh(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	overrides, err := rs.limitsSource.GetOverrides(ctx)
+	if err != nil {
+		zap.L().Warn("failed to refresh rate limit overrides, using the known ones", zap.Error(err))
+		return
+	}
+
+	rs.limiter.SetOverrides(overrides)
+	metrics.RateLimitOverrides.Set(float64(len(overrides)))
+}
+
+func (rs *RateLimitService) runNoticeWorkers(ctx context.Context, workerCount int) {
+	rs.noticeWg.Add(workerCount)
+
+	for i := 0; i < workerCount; i++ {
+		go func() {
+			defer rs.noticeWg.Done()
+			for {
+				select {
+				case notice, ok := <-rs.noticeChan:
+					if !ok {
+						return
+					}
+					resp := auth.NewErrorResponse(auth.CodeRateLimited, notice.CorrelationID)
+					resp.RetryAfterMs = notice.RetryAfter.Milliseconds()
+					if notice.Respond != nil {
+						if err := notice.Respond(resp); err != nil {
+							zap.L().Debug("failed to respond with throttle notice", zap.Error(err))
+						}
+						continue
+					}
+					payload, err := json.Marshal(resp)
+					if err != nil {
+						zap.L().Error("failed to marshal throttle notice", zap.Error(err))
+						continue
+					}
+					topic := "devices/" + notice.DeviceID + "/throttle"
+					err = rs.publisher.Publish(topic, payload)
+					if err != nil {
+						zap.L().Error("failed to publish throttle notice", zap.Error(err))
+						continue
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+}
+
+func NewRateLimitService(refreshInterval time.Duration, limiter limiter, limitsSource limitsSource,
+	publisher publisher) *RateLimitService {
+	return &RateLimitService{
+		noticeChan:      make(chan throttleNotice, 1024),
+		refreshInterval: refreshInterval,
+		limiter:         limiter,
+		limitsSource:    limitsSource,
+		publisher:       publisher,
+	}
+}
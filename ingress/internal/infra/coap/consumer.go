@@ -0,0 +1,499 @@
+package coap
+
+import (
+	"errors"
+	"go.uber.org/zap"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DangeL187/erax"
+
+	"ingress/internal/infra/metrics"
+	"ingress/internal/shared/auth"
+	"ingress/internal/shared/uplink"
+)
+
+const (
+	maxDatagramSize  = 1500
+	maxHandlers      = 1024
+	maxExchanges     = 100000
+	maxTransfers     = 10000
+	exchangeLifetime = 247 * time.Second // EXCHANGE_LIFETIME, RFC 7252 section 4.8.2
+	transferTimeout  = 30 * time.Second
+	sweepInterval    = 10 * time.Second
+	overloadMaxAge   = 1 // seconds a device should wait before retrying after 5.03
+)
+
+// contentFormats maps the registered CoAP Content-Format numbers to the media types of the codecs.
+var contentFormats = map[uint32]string{
+	50: "application/json",
+	60: "application/cbor",
+}
+
+type tokenVerifier interface {
+	Verify(deviceToken string) (auth.Identity, error)
+}
+
+type rateLimiter interface {
+	Admit(deviceID string, n int) (int, time.Duration, bool)
+}
+
+type decoder interface {
+	Decode(msg *uplink.Message) (uplink.Telemetry, string, error)
+	DecodeBatch(msg *uplink.Message) (uplink.Batch, string, string, error)
+}
+
+type response struct {
+	code    code
+	options []option
+	reason  string // diagnostic payload
+}
+
+// exchange remembers the response to a request, so that retransmissions are answered again instead of being processed
+// twice. A nil reply means the request is still being handled.
+type exchange struct {
+	reply     []byte
+	expiresAt time.Time
+}
+
+type transfer struct {
+	body      []byte
+	expiresAt time.Time
+}
+
+// Consumer is a CoAP server for constrained devices that cannot keep an MQTT session. Devices POST readings to
+// /telemetry (or /telemetry/<codec>) as confirmable or non-confirmable requests, with the token in the device token
+// option or in the payload; larger payloads are sent block-wise with Block1.
+//
+// Readings are decoded, authenticated and charged to the device's rate limit before they are enqueued, so the response
+// code tells the device the outcome: 2.04 once enqueued, 4.01/4.03 for auth failures, 4.29 with Max-Age when the
+// device is throttled and 5.03 with Max-Age when the message channel is full.
+type Consumer struct {
+	addr         string
+	maxBodyBytes int
+
+	conn     *net.UDPConn
+	decoder  decoder
+	verifier tokenVerifier
+	limiter  rateLimiter
+
+	messageHandler func(msg *uplink.Message) bool
+	messageID      atomic.Uint32
+
+	exchangesMu sync.Mutex
+	exchanges   map[string]*exchange
+
+	transfersMu sync.Mutex
+	transfers   map[string]*transfer
+
+	handlers  chan struct{}
+	handlerWg sync.WaitGroup
+	readDone  chan struct{}
+	done      chan struct{}
+}
+
+func (c *Consumer) Run(messageHandler func(msg *uplink.Message) bool) error {
+	c.messageHandler = messageHandler
+
+	udpAddr, err := net.ResolveUDPAddr("udp", c.addr)
+	if err != nil {
+		return erax.Wrap(err, "failed to resolve coap address")
+	}
+
+	c.conn, err = net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return erax.Wrap(err, "failed to listen for coap")
+	}
+
+	zap.L().Info("CoAP server started", zap.String("addr", c.addr))
+
+	go c.readLoop()
+	go c.sweepLoop()
+
+	return nil
+}
+
+func (c *Consumer) Stop() error {
+	close(c.done)
+
+	if c.conn == nil {
+		return nil
+	}
+
+	err := c.conn.Close()
+	<-c.readDone
+	c.handlerWg.Wait()
+
+	if err != nil {
+		return erax.Wrap(err, "failed to close coap connection")
+	}
+
+	return nil
+}
+
+func (c *Consumer) readLoop() {
+	defer close(c.readDone)
+
+	buf := make([]byte, maxDatagramSize)
+
+	for {
+		n, addr, err := c.conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			zap.L().Error("failed to read coap datagram", zap.Error(err))
+			continue
+		}
+
+		c.handleDatagram(buf[:n], addr)
+	}
+}
+
+func (c *Consumer) handleDatagram(data []byte, addr *net.UDPAddr) {
+	req, err := parseMessage(data)
+	if err != nil {
+		// a malformed confirmable message is rejected with a reset, anything else is silently ignored
+		if len(data) >= 4 && data[0]>>6 == 1 && messageType(data[0]>>4&0x03) == typeConfirmable {
+			rst := &message{Type: typeReset, Code: codeEmpty, MessageID: uint16(data[2])<<8 | uint16(data[3])}
+			c.send(rst.marshal(), addr)
+		}
+		return
+	}
+
+	if req.Type == typeAcknowledgement || req.Type == typeReset {
+		return
+	}
+	if req.Code == codeEmpty {
+		if req.Type == typeConfirmable {
+			// CoAP ping
+			c.send((&message{Type: typeReset, Code: codeEmpty, MessageID: req.MessageID}).marshal(), addr)
+		}
+		return
+	}
+	if req.Code>>5 != 0 {
+		return // a response, not a request
+	}
+
+	key := addr.String() + "/" + strconv.Itoa(int(req.MessageID))
+	if reply, seen := c.lookupExchange(key); seen {
+		if reply != nil {
+			c.send(reply, addr)
+		}
+		return
+	}
+
+	select {
+	case c.handlers <- struct{}{}:
+	default:
+		c.reply(req, addr, key, response{
+			code:    codeServiceUnavailable,
+			options: []option{uintOption(optionMaxAge, overloadMaxAge)},
+			reason:  "ingress is overloaded",
+		})
+		return
+	}
+
+	c.handlerWg.Add(1)
+	go func() {
+		defer c.handlerWg.Done()
+		defer func() { <-c.handlers }()
+
+		c.reply(req, addr, key, c.handleRequest(req, addr))
+	}()
+}
+
+func (c *Consumer) handleRequest(req *message, addr *net.UDPAddr) response {
+	// RFC 7252 section 5.4.1: unrecognized critical options must not be ignored
+	if id, ok := req.unknownCriticalOption(); ok {
+		return response{code: codeBadOption, reason: "unrecognized critical option " + strconv.Itoa(int(id))}
+	}
+
+	path := req.path()
+	if path != "telemetry" && !strings.HasPrefix(path, "telemetry/") {
+		return response{code: codeNotFound}
+	}
+	if req.Code != codePost {
+		return response{code: codeMethodNotAllowed}
+	}
+
+	var contentType string
+	if format, ok := req.uintOption(optionContentFormat); ok {
+		if contentType, ok = contentFormats[format]; !ok {
+			return response{code: codeUnsupportedContentFormat}
+		}
+	}
+
+	body := req.Payload
+	var blockOption []option
+	if value, ok := req.uintOption(optionBlock1); ok {
+		b, valid := parseBlock(value)
+		if !valid {
+			return response{code: codeBadRequest, reason: "invalid block1 option"}
+		}
+
+		var resp *response
+		body, resp = c.addBlock(addr.String()+"/"+path, b, req.Payload)
+		if resp != nil {
+			return *resp
+		}
+		if b.more {
+			return response{code: codeContinue, options: []option{b.option()}}
+		}
+		blockOption = []option{b.option()}
+	}
+
+	if len(body) > c.maxBodyBytes {
+		return response{
+			code:    codeRequestEntityTooLarge,
+			options: []option{uintOption(optionSize1, uint32(c.maxBodyBytes))},
+		}
+	}
+	if len(body) == 0 {
+		return response{code: codeBadRequest, reason: "empty payload"}
+	}
+
+	token, _ := req.option(optionDeviceToken)
+	resp := c.enqueue(&uplink.Message{
+		Topic:       "coap/" + path,
+		ContentType: contentType,
+		Token:       string(token),
+		Payload:     body,
+	})
+	resp.options = append(resp.options, blockOption...)
+
+	return resp
+}
+
+// enqueue decodes, authenticates and rate limits the reading before handing it over, so that the response can report
+// the outcome; the ProducerLoop takes the decoded reading and only checks it against the verified device.
+func (c *Consumer) enqueue(msg *uplink.Message) response {
+	decoded, err := c.decode(msg)
+	if errors.Is(err, uplink.ErrBatchTooLarge) {
+		return response{code: codeRequestEntityTooLarge, reason: uplink.ErrBatchTooLarge.Error()}
+	}
+	if err != nil {
+		return response{code: codeBadRequest, reason: "failed to decode payload"}
+	}
+
+	deviceID, token, readings := decoded.Telemetry.ID, decoded.Telemetry.Token, 1
+	if uplink.IsBatchTopic(msg.Topic) {
+		deviceID, token, readings = decoded.Batch.ID, decoded.Batch.Token, len(decoded.Batch.Readings)
+	}
+
+	if token == "" {
+		token = msg.Token
+	}
+	if token == "" {
+		return response{code: codeUnauthorized, reason: "missing token"}
+	}
+
+	identity, err := c.verifier.Verify(token)
+	if err != nil {
+		metrics.AuthFail.Inc()
+		zap.L().Debug("coap: invalid token", zap.Error(err))
+		switch code := auth.CodeOf(err); code {
+		case auth.CodeDeviceDisabled:
+			return response{code: codeForbidden, reason: string(code)}
+		case auth.CodeUnavailable:
+			return response{
+				code:    codeServiceUnavailable,
+				options: []option{uintOption(optionMaxAge, uint32(auth.UnavailableRetryAfter.Seconds()))},
+				reason:  string(code),
+			}
+		default:
+			return response{code: codeUnauthorized, reason: string(code)}
+		}
+	}
+	if identity.DeviceID != deviceID {
+		metrics.IdentityMismatch.Inc()
+		return response{code: codeForbidden, reason: string(auth.CodeIdentityMismatch)}
+	}
+
+	admitted, retryAfter, ok := c.limiter.Admit(identity.DeviceID, readings)
+	if !ok {
+		return response{
+			code:    codeTooManyRequests,
+			options: []option{uintOption(optionMaxAge, uint32(math.Ceil(retryAfter.Seconds())))},
+			reason:  string(auth.CodeRateLimited),
+		}
+	}
+
+	msg.Token = token
+	msg.DeviceID = identity.DeviceID
+	msg.Admitted = admitted
+	msg.Decoded = decoded
+	if !c.messageHandler(msg) {
+		return response{
+			code:    codeServiceUnavailable,
+			options: []option{uintOption(optionMaxAge, overloadMaxAge)},
+			reason:  "ingress is overloaded",
+		}
+	}
+
+	return response{code: codeChanged}
+}
+
+// decode decodes a reading, or a batch envelope posted to /telemetry/batch.
+func (c *Consumer) decode(msg *uplink.Message) (*uplink.Decoded, error) {
+	if uplink.IsBatchTopic(msg.Topic) {
+		batch, codecName, encoding, err := c.decoder.DecodeBatch(msg)
+		if err != nil {
+			return nil, err
+		}
+		return &uplink.Decoded{Batch: batch, Codec: codecName, Encoding: encoding}, nil
+	}
+
+	data, codecName, err := c.decoder.Decode(msg)
+	if err != nil {
+		return nil, err
+	}
+	return &uplink.Decoded{Telemetry: data, Codec: codecName}, nil
+}
+
+// addBlock appends a Block1 block to the transfer of key and returns the whole body after the last block. Blocks
+// must arrive in order; a gap or an unknown transfer is answered with 4.08 so that the device restarts it.
+func (c *Consumer) addBlock(key string, b block, payload []byte) ([]byte, *response) {
+	if b.more && len(payload) != b.size() {
+		return nil, &response{code: codeBadRequest, reason: "block does not match its size"}
+	}
+
+	c.transfersMu.Lock()
+	defer c.transfersMu.Unlock()
+
+	t, ok := c.transfers[key]
+	if b.num == 0 {
+		if !ok && len(c.transfers) >= maxTransfers {
+			return nil, &response{
+				code:    codeServiceUnavailable,
+				options: []option{uintOption(optionMaxAge, overloadMaxAge)},
+				reason:  "too many block-wise transfers",
+			}
+		}
+		t = &transfer{}
+		c.transfers[key] = t
+	} else if !ok || len(t.body) != int(b.num)*b.size() {
+		delete(c.transfers, key)
+		return nil, &response{code: codeRequestEntityIncomplete}
+	}
+
+	if len(t.body)+len(payload) > c.maxBodyBytes {
+		delete(c.transfers, key)
+		return nil, &response{
+			code:    codeRequestEntityTooLarge,
+			options: []option{uintOption(optionSize1, uint32(c.maxBodyBytes))},
+		}
+	}
+
+	t.body = append(t.body, payload...)
+	t.expiresAt = time.Now().Add(transferTimeout)
+	if b.more {
+		return nil, nil
+	}
+
+	delete(c.transfers, key)
+	return t.body, nil
+}
+
+// reply answers a confirmable request with a piggybacked acknowledgement and a non-confirmable one with a
+// non-confirmable response, and remembers the answer for retransmissions.
+func (c *Consumer) reply(req *message, addr *net.UDPAddr, key string, resp response) {
+	metrics.CoAPRequests.WithLabelValues(resp.code.String()).Inc()
+
+	msg := &message{
+		Type:      typeAcknowledgement,
+		Code:      resp.code,
+		MessageID: req.MessageID,
+		Token:     req.Token,
+		Options:   resp.options,
+		Payload:   []byte(resp.reason),
+	}
+	if req.Type == typeNonConfirmable {
+		msg.Type = typeNonConfirmable
+		msg.MessageID = uint16(c.messageID.Add(1))
+	}
+
+	reply := msg.marshal()
+	c.storeExchange(key, reply)
+	c.send(reply, addr)
+}
+
+func (c *Consumer) send(data []byte, addr *net.UDPAddr) {
+	if _, err := c.conn.WriteToUDP(data, addr); err != nil && !errors.Is(err, net.ErrClosed) {
+		zap.L().Debug("failed to send coap response", zap.Error(err))
+	}
+}
+
+// lookupExchange reports whether the request was seen before, registering it as in progress otherwise.
+func (c *Consumer) lookupExchange(key string) ([]byte, bool) {
+	c.exchangesMu.Lock()
+	defer c.exchangesMu.Unlock()
+
+	if e, ok := c.exchanges[key]; ok {
+		return e.reply, true
+	}
+	if len(c.exchanges) < maxExchanges {
+		c.exchanges[key] = &exchange{expiresAt: time.Now().Add(exchangeLifetime)}
+	}
+
+	return nil, false
+}
+
+func (c *Consumer) storeExchange(key string, reply []byte) {
+	c.exchangesMu.Lock()
+	defer c.exchangesMu.Unlock()
+
+	if e, ok := c.exchanges[key]; ok {
+		e.reply = reply
+	}
+}
+
+func (c *Consumer) sweepLoop() {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case now := <-ticker.C:
+			c.exchangesMu.Lock()
+			for key, e := range c.exchanges {
+				if now.After(e.expiresAt) {
+					delete(c.exchanges, key)
+				}
+			}
+			c.exchangesMu.Unlock()
+
+			c.transfersMu.Lock()
+			for key, t := range c.transfers {
+				if now.After(t.expiresAt) {
+					delete(c.transfers, key)
+				}
+			}
+			c.transfersMu.Unlock()
+		}
+	}
+}
+
+// NewConsumer creates a CoAP server on addr (host:port, usually port 5683) accepting bodies up to maxBodyBytes.
+func NewConsumer(addr string, decoder decoder, verifier tokenVerifier, limiter rateLimiter,
+	maxBodyBytes int) *Consumer {
+	return &Consumer{
+		addr:         addr,
+		maxBodyBytes: maxBodyBytes,
+		decoder:      decoder,
+		verifier:     verifier,
+		limiter:      limiter,
+		exchanges:    make(map[string]*exchange),
+		transfers:    make(map[string]*transfer),
+		handlers:     make(chan struct{}, maxHandlers),
+		readDone:     make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+}
@@ -0,0 +1,111 @@
+package coap
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestAddBlock(t *testing.T) {
+	full := bytes.Repeat([]byte{'a'}, 16)
+
+	type step struct {
+		block   block
+		payload []byte
+		want    []byte // body once the transfer completes
+		code    code   // response code, 0 when the block is accepted
+	}
+
+	tests := []struct {
+		name         string
+		maxBodyBytes int
+		steps        []step
+	}{
+		{"in order", 64, []step{
+			{block: block{num: 0, more: true}, payload: full},
+			{block: block{num: 1, more: true}, payload: full},
+			{block: block{num: 2}, payload: []byte("tail"), want: append(bytes.Repeat([]byte{'a'}, 32), "tail"...)},
+		}},
+		{"single last block", 64, []step{
+			{block: block{num: 0}, payload: []byte("only"), want: []byte("only")},
+		}},
+		{"restarted transfer", 64, []step{
+			{block: block{num: 0, more: true}, payload: full},
+			{block: block{num: 0}, payload: []byte("new"), want: []byte("new")},
+		}},
+		{"gap", 64, []step{
+			{block: block{num: 0, more: true}, payload: full},
+			{block: block{num: 2, more: true}, payload: full, code: codeRequestEntityIncomplete},
+		}},
+		{"unknown transfer", 64, []step{
+			{block: block{num: 1}, payload: []byte("tail"), code: codeRequestEntityIncomplete},
+		}},
+		{"repeated block", 64, []step{
+			{block: block{num: 0, more: true}, payload: full},
+			{block: block{num: 1, more: true}, payload: full},
+			{block: block{num: 1, more: true}, payload: full, code: codeRequestEntityIncomplete},
+		}},
+		{"block shorter than its size", 64, []step{
+			{block: block{num: 0, more: true}, payload: full[:8], code: codeBadRequest},
+		}},
+		{"over the body limit", 24, []step{
+			{block: block{num: 0, more: true}, payload: full},
+			{block: block{num: 1}, payload: full, code: codeRequestEntityTooLarge},
+		}},
+		{"transfer is dropped after an error", 64, []step{
+			{block: block{num: 0, more: true}, payload: full},
+			{block: block{num: 2, more: true}, payload: full, code: codeRequestEntityIncomplete},
+			{block: block{num: 1}, payload: full, code: codeRequestEntityIncomplete},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewConsumer("", nil, nil, nil, tt.maxBodyBytes)
+
+			for i, s := range tt.steps {
+				body, resp := c.addBlock("client/telemetry", s.block, s.payload)
+
+				var gotCode code
+				if resp != nil {
+					gotCode = resp.code
+				}
+				if gotCode != s.code {
+					t.Fatalf("step %d: code = %v, want %v", i, gotCode, s.code)
+				}
+				if !bytes.Equal(body, s.want) {
+					t.Fatalf("step %d: body = %q, want %q", i, body, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleRequestRejectsUnknownCriticalOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []option
+		want    code
+	}{
+		{"Uri-Query", []option{{ID: optionURIPath, Value: []byte("telemetry")}, {ID: 15, Value: []byte("a=b")}},
+			codeBadOption},
+		{"Block2", []option{{ID: optionURIPath, Value: []byte("telemetry")}, {ID: 23, Value: []byte{0x06}}},
+			codeBadOption},
+		{"unknown elective option is ignored", []option{{ID: optionURIPath, Value: []byte("other")}, {ID: 28}},
+			codeNotFound},
+		{"Uri-Host is understood", []option{{ID: optionURIHost, Value: []byte("ingress")},
+			{ID: optionURIPath, Value: []byte("other")}}, codeNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewConsumer("", nil, nil, nil, 1024)
+			req := &message{Type: typeConfirmable, Code: codePost, Options: tt.options, Payload: []byte("{}")}
+
+			resp := c.handleRequest(req, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5683})
+			if resp.code != tt.want {
+				t.Errorf("code = %v, want %v", resp.code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,302 @@
+package coap
+
+import (
+	"encoding/binary"
+	"errors"
+	"slices"
+)
+
+// The subset of CoAP (RFC 7252) and block-wise transfer (RFC 7959) the telemetry endpoint needs.
+
+type messageType uint8
+
+const (
+	typeConfirmable     messageType = 0
+	typeNonConfirmable  messageType = 1
+	typeAcknowledgement messageType = 2
+	typeReset           messageType = 3
+)
+
+type code uint8
+
+func newCode(class, detail uint8) code {
+	return code(class<<5 | detail)
+}
+
+var (
+	codeEmpty = newCode(0, 0)
+	codePost  = newCode(0, 2)
+
+	codeChanged  = newCode(2, 4)
+	codeContinue = newCode(2, 31)
+
+	codeBadRequest               = newCode(4, 0)
+	codeBadOption                = newCode(4, 2)
+	codeUnauthorized             = newCode(4, 1)
+	codeForbidden                = newCode(4, 3)
+	codeNotFound                 = newCode(4, 4)
+	codeMethodNotAllowed         = newCode(4, 5)
+	codeRequestEntityIncomplete  = newCode(4, 8)
+	codeRequestEntityTooLarge    = newCode(4, 13)
+	codeUnsupportedContentFormat = newCode(4, 15)
+	codeTooManyRequests          = newCode(4, 29) // RFC 8516
+
+	codeServiceUnavailable = newCode(5, 3)
+)
+
+// String formats the code as in the RFCs, e.g. "4.01".
+func (c code) String() string {
+	detail := uint8(c) & 0x1f
+	return string([]byte{'0' + uint8(c)>>5, '.', '0' + detail/10, '0' + detail%10})
+}
+
+type optionID uint16
+
+const (
+	optionURIHost       optionID = 3
+	optionURIPort       optionID = 7
+	optionURIPath       optionID = 11
+	optionContentFormat optionID = 12
+	optionMaxAge        optionID = 14
+	optionBlock1        optionID = 27
+	optionSize1         optionID = 60
+
+	// optionDeviceToken carries the device token outside the payload. 65000 is in the experimental-use range and,
+	// being even, elective: servers that do not know it ignore it.
+	optionDeviceToken optionID = 65000
+)
+
+// knownCriticalOptions are the critical options the server understands. Uri-Host and Uri-Port only address the
+// server itself, so they need no processing.
+var knownCriticalOptions = map[optionID]bool{
+	optionURIHost: true,
+	optionURIPort: true,
+	optionURIPath: true,
+	optionBlock1:  true,
+}
+
+// critical reports whether a recipient that does not understand the option must reject the message: odd option
+// numbers are critical, even ones elective (RFC 7252 section 5.4.6).
+func (id optionID) critical() bool {
+	return id&1 == 1
+}
+
+type option struct {
+	ID    optionID
+	Value []byte
+}
+
+type message struct {
+	Type      messageType
+	Code      code
+	MessageID uint16
+	Token     []byte
+	Options   []option
+	Payload   []byte
+}
+
+var errMessageFormat = errors.New("malformed coap message")
+
+func parseMessage(data []byte) (*message, error) {
+	if len(data) < 4 || data[0]>>6 != 1 {
+		return nil, errMessageFormat
+	}
+
+	tokenLength := int(data[0] & 0x0f)
+	if tokenLength > 8 || len(data) < 4+tokenLength {
+		return nil, errMessageFormat
+	}
+
+	msg := &message{
+		Type:      messageType(data[0] >> 4 & 0x03),
+		Code:      code(data[1]),
+		MessageID: binary.BigEndian.Uint16(data[2:4]),
+		Token:     slices.Clone(data[4 : 4+tokenLength]),
+	}
+
+	data = data[4+tokenLength:]
+	var id optionID
+	for len(data) > 0 {
+		if data[0] == 0xff {
+			if len(data) == 1 {
+				return nil, errMessageFormat
+			}
+			msg.Payload = slices.Clone(data[1:])
+			break
+		}
+
+		delta, length := int(data[0]>>4), int(data[0]&0x0f)
+		data = data[1:]
+
+		var err error
+		if delta, data, err = readExtended(delta, data); err != nil {
+			return nil, err
+		}
+		if length, data, err = readExtended(length, data); err != nil {
+			return nil, err
+		}
+		if len(data) < length {
+			return nil, errMessageFormat
+		}
+
+		if int(id)+delta > 0xffff {
+			return nil, errMessageFormat
+		}
+		id += optionID(delta)
+		msg.Options = append(msg.Options, option{ID: id, Value: slices.Clone(data[:length])})
+		data = data[length:]
+	}
+
+	return msg, nil
+}
+
+func readExtended(value int, data []byte) (int, []byte, error) {
+	switch value {
+	case 13:
+		if len(data) < 1 {
+			return 0, nil, errMessageFormat
+		}
+		return int(data[0]) + 13, data[1:], nil
+	case 14:
+		if len(data) < 2 {
+			return 0, nil, errMessageFormat
+		}
+		return int(binary.BigEndian.Uint16(data)) + 269, data[2:], nil
+	case 15:
+		return 0, nil, errMessageFormat
+	default:
+		return value, data, nil
+	}
+}
+
+func (m *message) marshal() []byte {
+	out := []byte{1<<6 | byte(m.Type)<<4 | byte(len(m.Token)), byte(m.Code), 0, 0}
+	binary.BigEndian.PutUint16(out[2:4], m.MessageID)
+	out = append(out, m.Token...)
+
+	options := slices.Clone(m.Options)
+	slices.SortStableFunc(options, func(a, b option) int {
+		return int(a.ID) - int(b.ID)
+	})
+
+	var previous optionID
+	for _, opt := range options {
+		delta, length := int(opt.ID-previous), len(opt.Value)
+		previous = opt.ID
+
+		deltaNibble, deltaExt := extended(delta)
+		lengthNibble, lengthExt := extended(length)
+		out = append(out, byte(deltaNibble<<4|lengthNibble))
+		out = append(out, deltaExt...)
+		out = append(out, lengthExt...)
+		out = append(out, opt.Value...)
+	}
+
+	if len(m.Payload) > 0 {
+		out = append(out, 0xff)
+		out = append(out, m.Payload...)
+	}
+
+	return out
+}
+
+func extended(value int) (int, []byte) {
+	switch {
+	case value < 13:
+		return value, nil
+	case value < 269:
+		return 13, []byte{byte(value - 13)}
+	default:
+		return 14, binary.BigEndian.AppendUint16(nil, uint16(value-269))
+	}
+}
+
+func (m *message) option(id optionID) ([]byte, bool) {
+	for _, opt := range m.Options {
+		if opt.ID == id {
+			return opt.Value, true
+		}
+	}
+
+	return nil, false
+}
+
+// unknownCriticalOption returns the first critical option the server does not understand, if any.
+func (m *message) unknownCriticalOption() (optionID, bool) {
+	for _, opt := range m.Options {
+		if opt.ID.critical() && !knownCriticalOptions[opt.ID] {
+			return opt.ID, true
+		}
+	}
+
+	return 0, false
+}
+
+func (m *message) uintOption(id optionID) (uint32, bool) {
+	value, ok := m.option(id)
+	if !ok || len(value) > 4 {
+		return 0, false
+	}
+
+	var n uint32
+	for _, b := range value {
+		n = n<<8 | uint32(b)
+	}
+
+	return n, true
+}
+
+// path joins the Uri-Path options, e.g. "telemetry".
+func (m *message) path() string {
+	var path []byte
+	for _, opt := range m.Options {
+		if opt.ID == optionURIPath {
+			if path != nil {
+				path = append(path, '/')
+			}
+			path = append(path, opt.Value...)
+		}
+	}
+
+	return string(path)
+}
+
+func uintOption(id optionID, n uint32) option {
+	value := binary.BigEndian.AppendUint32(nil, n)
+	for len(value) > 0 && value[0] == 0 {
+		value = value[1:]
+	}
+
+	return option{ID: id, Value: value}
+}
+
+// block is a Block1 option value: the block number, whether more blocks follow, and the block size exponent
+// (size = 2^(szx+4), 16 to 1024 bytes).
+type block struct {
+	num  uint32
+	more bool
+	szx  uint8
+}
+
+func parseBlock(value uint32) (block, bool) {
+	b := block{
+		num:  value >> 4,
+		more: value&0x08 != 0,
+		szx:  uint8(value & 0x07),
+	}
+
+	return b, b.szx < 7
+}
+
+func (b block) size() int {
+	return 1 << (b.szx + 4)
+}
+
+func (b block) option() option {
+	value := b.num<<4 | uint32(b.szx)
+	if b.more {
+		value |= 0x08
+	}
+
+	return uintOption(optionBlock1, value)
+}
@@ -0,0 +1,156 @@
+package coap
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestParseMessageOptionExtensions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []option
+	}{
+		{"4-bit delta and length", []option{{ID: optionURIPath, Value: []byte("telemetry")}}},
+		{"8-bit delta", []option{{ID: optionSize1, Value: []byte{1}}}},
+		{"8-bit delta upper bound", []option{{ID: 268, Value: []byte{1}}}},
+		{"16-bit delta lower bound", []option{{ID: 269, Value: []byte{1}}}},
+		{"16-bit delta", []option{{ID: optionDeviceToken, Value: []byte("token")}}},
+		{"8-bit length", []option{{ID: optionDeviceToken, Value: bytes.Repeat([]byte{'a'}, 13)}}},
+		{"8-bit length upper bound", []option{{ID: optionDeviceToken, Value: bytes.Repeat([]byte{'a'}, 268)}}},
+		{"16-bit length", []option{{ID: optionDeviceToken, Value: bytes.Repeat([]byte{'a'}, 600)}}},
+		{"zero delta for repeated options", []option{
+			{ID: optionURIPath, Value: []byte("telemetry")},
+			{ID: optionURIPath, Value: []byte("cbor")},
+		}},
+		{"extended delta after a small one", []option{
+			{ID: optionURIPath, Value: []byte("telemetry")},
+			{ID: optionContentFormat, Value: []byte{60}},
+			{ID: optionBlock1, Value: []byte{0x0e}},
+			{ID: optionDeviceToken, Value: []byte("token")},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := &message{
+				Type:      typeConfirmable,
+				Code:      codePost,
+				MessageID: 0x1234,
+				Token:     []byte{1, 2, 3, 4},
+				Options:   tt.options,
+				Payload:   []byte(`{"id":"dev-1"}`),
+			}
+
+			out, err := parseMessage(in.marshal())
+			if err != nil {
+				t.Fatalf("parseMessage() error = %v", err)
+			}
+			if out.Type != in.Type || out.Code != in.Code || out.MessageID != in.MessageID {
+				t.Errorf("header = %v %v %v, want %v %v %v", out.Type, out.Code, out.MessageID, in.Type, in.Code,
+					in.MessageID)
+			}
+			if !bytes.Equal(out.Token, in.Token) {
+				t.Errorf("token = %x, want %x", out.Token, in.Token)
+			}
+			if len(out.Options) != len(in.Options) {
+				t.Fatalf("got %d options, want %d", len(out.Options), len(in.Options))
+			}
+			for i := range in.Options {
+				if out.Options[i].ID != in.Options[i].ID || !bytes.Equal(out.Options[i].Value, in.Options[i].Value) {
+					t.Errorf("option %d = %d %q, want %d %q", i, out.Options[i].ID, out.Options[i].Value,
+						in.Options[i].ID, in.Options[i].Value)
+				}
+			}
+			if !bytes.Equal(out.Payload, in.Payload) {
+				t.Errorf("payload = %q, want %q", out.Payload, in.Payload)
+			}
+		})
+	}
+}
+
+func TestParseMessageMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"shorter than the header", []byte{0x40, 0x02, 0x00}},
+		{"version 0", []byte{0x00, 0x02, 0x00, 0x01}},
+		{"version 2", []byte{0x80, 0x02, 0x00, 0x01}},
+		{"token length 9", []byte{0x49, 0x02, 0x00, 0x01, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{"truncated token", []byte{0x44, 0x02, 0x00, 0x01, 1, 2}},
+		{"payload marker without payload", []byte{0x40, 0x02, 0x00, 0x01, 0xff}},
+		{"reserved delta 15", []byte{0x40, 0x02, 0x00, 0x01, 0xf1, 'a'}},
+		{"reserved length 15", []byte{0x40, 0x02, 0x00, 0x01, 0xbf, 'a'}},
+		{"truncated 8-bit delta", []byte{0x40, 0x02, 0x00, 0x01, 0xd1}},
+		{"truncated 16-bit delta", []byte{0x40, 0x02, 0x00, 0x01, 0xe1, 0x00}},
+		{"truncated 8-bit length", []byte{0x40, 0x02, 0x00, 0x01, 0xbd}},
+		{"truncated 16-bit length", []byte{0x40, 0x02, 0x00, 0x01, 0xbe, 0x00}},
+		{"value longer than the message", []byte{0x40, 0x02, 0x00, 0x01, 0xb5, 't', 'e'}},
+		{"option number over 65535", []byte{0x40, 0x02, 0x00, 0x01, 0xe0, 0xfe, 0xf3, 0xe0, 0x00, 0x00}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseMessage(tt.data); !errors.Is(err, errMessageFormat) {
+				t.Errorf("parseMessage() error = %v, want %v", err, errMessageFormat)
+			}
+		})
+	}
+}
+
+func TestUnknownCriticalOption(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []option
+		want    optionID
+		found   bool
+	}{
+		{"none", nil, 0, false},
+		{"known critical", []option{{ID: optionURIHost}, {ID: optionURIPort}, {ID: optionURIPath}, {ID: optionBlock1}},
+			0, false},
+		{"unknown elective", []option{{ID: optionContentFormat}, {ID: 28}, {ID: optionDeviceToken}}, 0, false},
+		{"Uri-Query", []option{{ID: optionURIPath}, {ID: 15}}, 15, true},
+		{"Block2", []option{{ID: 23}}, 23, true},
+		{"experimental critical", []option{{ID: 65001}}, 65001, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &message{Options: tt.options}
+			id, found := msg.unknownCriticalOption()
+			if id != tt.want || found != tt.found {
+				t.Errorf("unknownCriticalOption() = %d %v, want %d %v", id, found, tt.want, tt.found)
+			}
+		})
+	}
+}
+
+func TestParseBlock(t *testing.T) {
+	tests := []struct {
+		name  string
+		value uint32
+		want  block
+		valid bool
+	}{
+		{"first of more, 16 bytes", 0x08, block{num: 0, more: true, szx: 0}, true},
+		{"last, 1024 bytes", 0x36, block{num: 3, more: false, szx: 6}, true},
+		{"20-bit block number", 0xfffff6, block{num: 0xfffff, more: false, szx: 6}, true},
+		{"reserved szx 7", 0x07, block{szx: 7}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, valid := parseBlock(tt.value)
+			if got != tt.want || valid != tt.valid {
+				t.Errorf("parseBlock(%#x) = %+v %v, want %+v %v", tt.value, got, valid, tt.want, tt.valid)
+			}
+			if valid {
+				if value, _ := (&message{Options: []option{got.option()}}).uintOption(optionBlock1); value != tt.value {
+					t.Errorf("option() = %#x, want %#x", value, tt.value)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package grpcingest
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/DangeL187/erax"
+
+	pb "ingress/internal/infra/grpc/proto/ingest"
+	"ingress/internal/infra/metrics"
+	"ingress/internal/shared/auth"
+	"ingress/internal/shared/uplink"
+)
+
+// Topic is the uplink topic of readings received over gRPC, so that routes and the Kafka topic header can tell them
+// apart from MQTT uplinks.
+const Topic = "grpc/telemetry"
+
+const (
+	ackBufferSize   = 256
+	overloadRetryMs = 1000
+	stopTimeout     = 5 * time.Second
+)
+
+type tokenVerifier interface {
+	Verify(deviceToken string) (auth.Identity, error)
+}
+
+type rateLimiter interface {
+	Admit(deviceID string, n int) (int, time.Duration, bool)
+}
+
+// Consumer serves the TelemetryIngest stream. Each stream is authenticated once when it is opened, and every reading
+// is acknowledged on the same stream as soon as it is enqueued or refused.
+type Consumer struct {
+	pb.UnimplementedTelemetryIngestServer
+
+	addr       string
+	grpcServer *grpc.Server
+	verifier   tokenVerifier
+	limiter    rateLimiter
+
+	messageHandler func(msg *uplink.Message) bool
+}
+
+func (c *Consumer) Run(messageHandler func(msg *uplink.Message) bool) error {
+	c.messageHandler = messageHandler
+
+	listener, err := net.Listen("tcp", c.addr)
+	if err != nil {
+		return erax.Wrap(err, "failed to listen for grpc ingest")
+	}
+
+	go func() {
+		zap.L().Info("gRPC ingest server started", zap.String("addr", c.addr))
+
+		if err := c.grpcServer.Serve(listener); err != nil {
+			zap.L().Error("grpc ingest server failed", zap.Error(err))
+		}
+	}()
+
+	return nil
+}
+
+// Stop waits for open streams to finish for a while before cutting them off, as gateway streams may never end on
+// their own.
+func (c *Consumer) Stop() error {
+	stopped := make(chan struct{})
+	go func() {
+		c.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(stopTimeout):
+		c.grpcServer.Stop()
+	}
+
+	return nil
+}
+
+func (c *Consumer) Stream(stream grpc.BidiStreamingServer[pb.Reading, pb.ReadingAck]) error {
+	token, err := bearerToken(stream.Context())
+	if err != nil {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	identity, err := c.verifier.Verify(token)
+	if err != nil {
+		metrics.AuthFail.Inc()
+		zap.L().Debug("grpc ingest: invalid token", zap.Error(err))
+		switch code := auth.CodeOf(err); code {
+		case auth.CodeDeviceDisabled:
+			return status.Error(codes.PermissionDenied, string(code))
+		case auth.CodeUnavailable:
+			return status.Error(codes.Unavailable, string(code))
+		default:
+			return status.Error(codes.Unauthenticated, string(code))
+		}
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	// grpc streams must not be sent to concurrently, so acks from the receive loop and auth errors from the
+	// AuthService go through one sender, which flushes the pending acks once the client stops sending
+	acks := make(chan *pb.ReadingAck, ackBufferSize)
+	recvDone := make(chan struct{})
+	senderDone := make(chan error, 1)
+	go func() {
+		senderDone <- sendAcks(ctx, stream, acks, recvDone)
+		cancel()
+	}()
+
+	for {
+		reading, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			close(recvDone)
+			return <-senderDone
+		}
+		if err != nil {
+			return err
+		}
+
+		if !identity.ExpiresAt.IsZero() && time.Now().After(identity.ExpiresAt) {
+			return status.Error(codes.Unauthenticated, string(auth.CodeTokenExpired))
+		}
+
+		ack := c.handleReading(ctx, identity, token, reading, acks)
+
+		select {
+		case acks <- ack:
+		case <-ctx.Done():
+			select {
+			case err = <-senderDone:
+				if err != nil {
+					return err
+				}
+			default:
+			}
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
+
+func sendAcks(ctx context.Context, stream grpc.BidiStreamingServer[pb.Reading, pb.ReadingAck],
+	acks <-chan *pb.ReadingAck, recvDone <-chan struct{}) error {
+	for {
+		select {
+		case ack := <-acks:
+			if err := stream.Send(ack); err != nil {
+				return err
+			}
+		case <-recvDone:
+			for {
+				select {
+				case ack := <-acks:
+					if err := stream.Send(ack); err != nil {
+						return err
+					}
+				default:
+					return nil
+				}
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// handleReading enqueues a reading bound to the identity verified at stream start, so that the ProducerLoop does not
+// look its token up again and rejects readings for other devices. Readings are charged to the device's rate limit
+// before they are acknowledged, so that throttled ones are refused instead of being dropped after ACCEPTED.
+func (c *Consumer) handleReading(ctx context.Context, identity auth.Identity, token string, reading *pb.Reading,
+	acks chan<- *pb.ReadingAck) *pb.ReadingAck {
+	id := reading.GetId()
+
+	admitted, retryAfter, ok := c.limiter.Admit(identity.DeviceID, 1)
+	if !ok {
+		resp := auth.NewErrorResponse(auth.CodeRateLimited, "")
+		return &pb.ReadingAck{
+			Id:           id,
+			Status:       pb.ReadingAck_THROTTLED,
+			Error:        resp.Error,
+			RetryAfterMs: uint32(retryAfter.Milliseconds()),
+			ErrorCode:    string(resp.Code),
+		}
+	}
+
+	accepted := c.messageHandler(&uplink.Message{
+		Topic:       Topic,
+		ContentType: reading.GetContentType(),
+		TraceParent: reading.GetTraceparent(),
+		Token:       token,
+		DeviceID:    identity.DeviceID,
+		Admitted:    admitted,
+		Payload:     reading.GetPayload(),
+		Reply: uplink.Reply{
+			Respond: func(resp auth.ErrorResponse) error {
+				ack := &pb.ReadingAck{
+					Id:        id,
+					Status:    pb.ReadingAck_AUTH_FAILED,
+					Error:     resp.Error,
+					ErrorCode: string(resp.Code),
+				}
+				if resp.Code == auth.CodeRateLimited {
+					ack.Status = pb.ReadingAck_THROTTLED
+					ack.RetryAfterMs = uint32(resp.RetryAfterMs)
+				}
+				select {
+				case acks <- ack:
+					return nil
+				case <-ctx.Done():
+					return errors.New("grpc ingest stream is closed")
+				default:
+					return errors.New("grpc ingest stream is not keeping up with acks")
+				}
+			},
+		},
+	})
+	if !accepted {
+		return &pb.ReadingAck{
+			Id:           id,
+			Status:       pb.ReadingAck_OVERLOADED,
+			Error:        "ingress is overloaded",
+			RetryAfterMs: overloadRetryMs,
+		}
+	}
+
+	return &pb.ReadingAck{Id: id, Status: pb.ReadingAck_ACCEPTED}
+}
+
+func bearerToken(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		if token, ok := strings.CutPrefix(value, "Bearer "); ok && token != "" {
+			return token, nil
+		}
+	}
+
+	return "", errors.New("missing bearer token")
+}
+
+// NewConsumer creates a TelemetryIngest server on addr, over TLS unless tlsConfig is nil.
+func NewConsumer(addr string, tlsConfig *tls.Config, verifier tokenVerifier, limiter rateLimiter) *Consumer {
+	var opts []grpc.ServerOption
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	c := &Consumer{
+		addr:       addr,
+		grpcServer: grpc.NewServer(opts...),
+		verifier:   verifier,
+		limiter:    limiter,
+	}
+	pb.RegisterTelemetryIngestServer(c.grpcServer, c)
+
+	return c
+}
@@ -0,0 +1,285 @@
+package httpingest
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"go.uber.org/zap"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DangeL187/erax"
+
+	"ingress/internal/infra/metrics"
+	"ingress/internal/shared/auth"
+	"ingress/internal/shared/uplink"
+)
+
+// Topic and BatchTopic are the uplink topics of readings and batch envelopes received over HTTP, so that routes and
+// the Kafka topic header can tell them apart from MQTT uplinks.
+const (
+	Topic      = "http/telemetry"
+	BatchTopic = "http/telemetry/batch"
+)
+
+type tokenVerifier interface {
+	Verify(deviceToken string) (auth.Identity, error)
+}
+
+type rateLimiter interface {
+	Admit(deviceID string, n int) (int, time.Duration, bool)
+}
+
+type decoder interface {
+	Decode(msg *uplink.Message) (uplink.Telemetry, string, error)
+	DecodeBatch(msg *uplink.Message) (uplink.Batch, string, string, error)
+}
+
+// Consumer accepts telemetry over HTTP for gateways that cannot speak MQTT:
+//
+//	POST /telemetry        one reading, in any codec selected by Content-Type (JSON by default)
+//	POST /telemetry/batch  a batch envelope, like on MQTT batch topics
+//
+// The device token goes in an "Authorization: Bearer <token>" header, and readings must be for the device it was
+// issued to. Requests are accepted with 202 once they are enqueued; when the device is throttled or the message channel
+// is full they are refused with 429, and the device should resend them after Retry-After.
+type Consumer struct {
+	server   *http.Server
+	decoder  decoder
+	verifier tokenVerifier
+	limiter  rateLimiter
+
+	maxBodyBytes int64
+
+	messageHandler func(msg *uplink.Message) bool
+}
+
+type response struct {
+	Accepted     int            `json:"accepted"`
+	Code         auth.ErrorCode `json:"code,omitempty"`
+	Error        string         `json:"error,omitempty"`
+	RetryAfterMs int64          `json:"retry_after_ms,omitempty"`
+}
+
+func (c *Consumer) Run(messageHandler func(msg *uplink.Message) bool) error {
+	c.messageHandler = messageHandler
+
+	listener, err := net.Listen("tcp", c.server.Addr)
+	if err != nil {
+		return erax.Wrap(err, "failed to listen for http ingest")
+	}
+
+	go func() {
+		zap.L().Info("HTTP ingest server started", zap.String("addr", c.server.Addr))
+
+		var serveErr error
+		if c.server.TLSConfig != nil {
+			serveErr = c.server.ServeTLS(listener, "", "")
+		} else {
+			serveErr = c.server.Serve(listener)
+		}
+		if serveErr != nil && !errors.Is(serveErr, http.ErrServerClosed) {
+			zap.L().Error("http ingest server failed", zap.Error(serveErr))
+		}
+	}()
+
+	return nil
+}
+
+func (c *Consumer) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := c.server.Shutdown(ctx); err != nil {
+		return erax.Wrap(err, "failed to shutdown http ingest server")
+	}
+
+	return nil
+}
+
+func (c *Consumer) handleSingle(w http.ResponseWriter, r *http.Request) {
+	msg, identity, ok := c.readRequest(w, r, Topic)
+	if !ok {
+		return
+	}
+
+	data, codecName, err := c.decoder.Decode(msg)
+	if err != nil {
+		c.respond(w, http.StatusBadRequest, response{Error: "failed to decode payload"})
+		return
+	}
+	if data.ID != identity.DeviceID {
+		c.forbid(w)
+		return
+	}
+
+	// the ProducerLoop takes the decoded reading instead of decoding the payload again
+	msg.Decoded = &uplink.Decoded{Telemetry: data, Codec: codecName}
+	c.enqueue(w, msg, 1)
+}
+
+func (c *Consumer) handleBatch(w http.ResponseWriter, r *http.Request) {
+	msg, identity, ok := c.readRequest(w, r, BatchTopic)
+	if !ok {
+		return
+	}
+
+	batch, codecName, encoding, err := c.decoder.DecodeBatch(msg)
+	if errors.Is(err, uplink.ErrBatchTooLarge) {
+		c.respond(w, http.StatusRequestEntityTooLarge, response{Error: uplink.ErrBatchTooLarge.Error()})
+		return
+	}
+	if err != nil {
+		c.respond(w, http.StatusBadRequest, response{Error: "failed to decode batch"})
+		return
+	}
+	if batch.ID != identity.DeviceID {
+		c.forbid(w)
+		return
+	}
+	for _, reading := range batch.Readings {
+		if reading.ID != "" && reading.ID != identity.DeviceID {
+			c.forbid(w)
+			return
+		}
+	}
+
+	msg.Decoded = &uplink.Decoded{Batch: batch, Codec: codecName, Encoding: encoding}
+	c.enqueue(w, msg, len(batch.Readings))
+}
+
+// readRequest authenticates the request and reads its body into a message for topic, answering the request itself
+// when either fails.
+func (c *Consumer) readRequest(w http.ResponseWriter, r *http.Request, topic string) (*uplink.Message, auth.Identity,
+	bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		c.respond(w, http.StatusUnauthorized, response{Error: "missing bearer token"})
+		return nil, auth.Identity{}, false
+	}
+
+	identity, err := c.verifier.Verify(token)
+	if err != nil {
+		metrics.AuthFail.Inc()
+		zap.L().Debug("http ingest: invalid token", zap.Error(err))
+		resp := auth.NewErrorResponse(auth.CodeOf(err), "")
+		switch resp.Code {
+		case auth.CodeDeviceDisabled:
+			c.respond(w, http.StatusForbidden, response{Code: resp.Code, Error: resp.Error})
+			return nil, auth.Identity{}, false
+		case auth.CodeUnavailable:
+			w.Header().Set("Retry-After", strconv.Itoa(int(auth.UnavailableRetryAfter.Seconds())))
+			c.respond(w, http.StatusServiceUnavailable, response{
+				Code:         resp.Code,
+				Error:        resp.Error,
+				RetryAfterMs: resp.RetryAfterMs,
+			})
+			return nil, auth.Identity{}, false
+		}
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		c.respond(w, http.StatusUnauthorized, response{Code: resp.Code, Error: resp.Error})
+		return nil, auth.Identity{}, false
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, c.maxBodyBytes))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.respond(w, http.StatusRequestEntityTooLarge, response{
+				Error: "body exceeds " + strconv.FormatInt(c.maxBodyBytes, 10) + " bytes",
+			})
+			return nil, auth.Identity{}, false
+		}
+		c.respond(w, http.StatusBadRequest, response{Error: "failed to read body"})
+		return nil, auth.Identity{}, false
+	}
+	if len(body) == 0 {
+		c.respond(w, http.StatusBadRequest, response{Error: "empty body"})
+		return nil, auth.Identity{}, false
+	}
+
+	return &uplink.Message{
+		ReceivedAt:  time.Now(),
+		Topic:       topic,
+		ContentType: r.Header.Get("Content-Type"),
+		TraceParent: r.Header.Get("traceparent"),
+		Token:       token,
+		DeviceID:    identity.DeviceID,
+		Payload:     body,
+	}, identity, true
+}
+
+// forbid answers a request with readings for another device than the one its token was issued to.
+func (c *Consumer) forbid(w http.ResponseWriter) {
+	metrics.IdentityMismatch.Inc()
+	resp := auth.NewErrorResponse(auth.CodeIdentityMismatch, "")
+	c.respond(w, http.StatusForbidden, response{Code: resp.Code, Error: resp.Error})
+}
+
+// enqueue charges msg, carrying readings readings, to the device's rate limit and hands it to the message handler,
+// which only refuses it when the message channel is full. Readings beyond what the device had tokens for are still
+// enqueued, and charged again by the ProducerLoop.
+func (c *Consumer) enqueue(w http.ResponseWriter, msg *uplink.Message, readings int) {
+	admitted, retryAfter, ok := c.limiter.Admit(msg.DeviceID, readings)
+	if !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		resp := auth.NewErrorResponse(auth.CodeRateLimited, "")
+		c.respond(w, http.StatusTooManyRequests, response{
+			Code:         resp.Code,
+			Error:        resp.Error,
+			RetryAfterMs: retryAfter.Milliseconds(),
+		})
+		return
+	}
+	msg.Admitted = admitted
+
+	if !c.messageHandler(msg) {
+		w.Header().Set("Retry-After", "1")
+		c.respond(w, http.StatusTooManyRequests, response{Error: "ingress is overloaded"})
+		return
+	}
+
+	c.respond(w, http.StatusAccepted, response{Accepted: readings})
+}
+
+func (c *Consumer) respond(w http.ResponseWriter, status int, resp response) {
+	metrics.HTTPIngestRequests.WithLabelValues(strconv.Itoa(status)).Inc()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		zap.L().Debug("failed to write http ingest response", zap.Error(err))
+	}
+}
+
+// NewConsumer creates an HTTP ingest server on addr, over TLS unless tlsConfig is nil.
+func NewConsumer(addr string, tlsConfig *tls.Config, decoder decoder, verifier tokenVerifier, limiter rateLimiter,
+	maxBodyBytes int64) *Consumer {
+	c := &Consumer{
+		decoder:      decoder,
+		verifier:     verifier,
+		limiter:      limiter,
+		maxBodyBytes: maxBodyBytes,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /telemetry", c.handleSingle)
+	mux.HandleFunc("POST /telemetry/batch", c.handleBatch)
+
+	c.server = &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+	}
+
+	return c
+}
@@ -23,12 +23,122 @@ var (
 			Help: "Messages failed authentication",
 		},
 	)
+	AuthCacheHits = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "auth_cache_hits_total",
+			Help: "Device tokens found in the verified-token cache",
+		},
+	)
+	AuthCacheMisses = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "auth_cache_misses_total",
+			Help: "Device tokens missing from or expired in the verified-token cache",
+		},
+	)
+	IdentityMismatch = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "messages_identity_mismatch_total",
+			Help: "Messages whose device ID differs from the token's device ID",
+		},
+	)
+	MessagesDecoded = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "messages_decoded_total",
+			Help: "Messages decoded, by payload codec",
+		},
+		[]string{"codec"},
+	)
+	BatchReadings = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "batch_readings",
+			Help:    "Readings per decoded batch envelope",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 11), // 1 to 1024
+		},
+	)
+	BatchBytes = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "batch_payload_bytes",
+			Help:    "Size of batch envelopes as received, by compression",
+			Buckets: prometheus.ExponentialBuckets(256, 4, 8), // 256 B to 4 MiB
+		},
+		[]string{"encoding"},
+	)
+	MessagesRejected = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "messages_rejected_total",
+			Help: "Messages rejected before reaching Kafka, by reason",
+		},
+		[]string{"reason"},
+	)
+	MessagesDuplicate = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "messages_duplicate_total",
+			Help: "Messages dropped because their sequence number was already received",
+		},
+	)
+	MessagesLost = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "messages_lost_total",
+			Help: "Sequence numbers never received from a device, by device",
+		},
+		[]string{"device_id"},
+	)
+	SequenceResets = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "sequence_resets_total",
+			Help: "Devices whose sequence number restarted far behind the duplicate window",
+		},
+	)
+	MessagesThrottled = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "messages_throttled_total",
+			Help: "Messages dropped because their device exceeded its rate limit",
+		},
+	)
+	RateLimitOverrides = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "rate_limit_overrides",
+			Help: "Devices with a rate limit override fetched from the auth service",
+		},
+	)
+	DisabledDevices = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "disabled_devices",
+			Help: "Devices disabled in the auth service, whose messages are rejected",
+		},
+	)
+	HTTPIngestRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_ingest_requests_total",
+			Help: "Requests to the HTTP ingest endpoints, by status code",
+		},
+		[]string{"code"},
+	)
+	CoAPRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "coap_requests_total",
+			Help: "Requests to the CoAP server, by response code",
+		},
+		[]string{"code"},
+	)
 	MessagesDropped = prometheus.NewCounter(
 		prometheus.CounterOpts{
 			Name: "messages_dropped_total",
 			Help: "Messages dropped due to full channel",
 		},
 	)
+	MessagesBackpressured = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "messages_backpressured_total",
+			Help: "Messages that had to wait for space in the channel",
+		},
+	)
+	MessagesUnacked = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "messages_unacked_total",
+			Help: "Messages left unacknowledged for broker redelivery due to full channel",
+		},
+	)
 	ConsumerLatency = prometheus.NewHistogram(
 		prometheus.HistogramOpts{
 			Name:    "consumer_latency_seconds",
@@ -42,15 +152,52 @@ var (
 			Help: "Messages successfully sent to Kafka",
 		},
 	)
+	ClockSkew = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "device_clock_skew_seconds",
+			Help:    "Difference between the device timestamp and the time ingress received the message, by direction",
+			Buckets: []float64{1, 5, 30, 60, 300, 900, 3600, 21600, 86400, 604800},
+		},
+		[]string{"direction"}, // "ahead" when the device clock is ahead of ingress, "behind" otherwise
+	)
+	ClockSkewed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "messages_clock_skewed_total",
+			Help: "Messages whose device timestamp is outside the clock skew window, by direction",
+		},
+		[]string{"direction"},
+	)
+	MessagesRouted = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "messages_routed_total",
+			Help: "Messages routed to Kafka, by route",
+		},
+		[]string{"route"},
+	)
 	MessagesSendErrors = prometheus.NewCounter(
 		prometheus.CounterOpts{
 			Name: "messages_send_errors_total",
 			Help: "Errors while sending messages to Kafka",
 		},
 	)
+	MessagesDeadLettered = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "messages_dead_lettered_total",
+			Help: "Messages moved to the dead-letter topic after exhausting retries",
+		},
+	)
+	DeadLetterErrors = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "messages_dead_letter_errors_total",
+			Help: "Messages lost because the dead-letter topic could not be written",
+		},
+	)
 )
 
 func RegisterAll() {
-	prometheus.MustRegister(MessagesReceived, AuthSuccess, AuthFail,
-		MessagesDropped, ConsumerLatency, MessagesSent, MessagesSendErrors)
+	prometheus.MustRegister(MessagesReceived, AuthSuccess, AuthFail, AuthCacheHits, AuthCacheMisses, IdentityMismatch,
+		MessagesDecoded, BatchReadings, BatchBytes, MessagesRejected, MessagesDuplicate, MessagesLost, SequenceResets,
+		MessagesThrottled, RateLimitOverrides, DisabledDevices, HTTPIngestRequests, CoAPRequests, MessagesDropped,
+		MessagesBackpressured, MessagesUnacked, ConsumerLatency, ClockSkew, ClockSkewed, MessagesSent, MessagesRouted,
+		MessagesSendErrors, MessagesDeadLettered, DeadLetterErrors)
 }
@@ -1,31 +1,75 @@
 package mqtt
 
 import (
+	"go.uber.org/zap"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"github.com/DangeL187/erax"
 	"github.com/eclipse/paho.mqtt.golang"
+
+	"ingress/internal/shared/uplink"
 )
 
 type Consumer struct {
 	mqttClient mqtt.Client
 	mqttTopic  string
+	qos        byte
+
+	// manualAck leaves refused messages unacknowledged and reconnects after resumeDelay, as the broker only
+	// redelivers them when the session resumes.
+	manualAck   bool
+	resumeDelay time.Duration
+
+	callback mqtt.MessageHandler
+
+	// resuming skips messages that arrive after a refused one, so that they are redelivered along with it
+	resuming atomic.Bool
+
+	mu      sync.Mutex
+	stopped bool
 }
 
-func (c *Consumer) Run(messageHandler func([]byte)) error {
-	if token := c.mqttClient.Connect(); token.Wait() && token.Error() != nil {
-		return erax.Wrap(token.Error(), "failed to connect to mqtt broker")
+// SharedTopic turns topic into a shared subscription of group, so that the broker delivers each message to only one
+// of the subscribers in the group instead of all of them.
+func SharedTopic(group, topic string) string {
+	if group == "" || strings.HasPrefix(topic, "$share/") {
+		return topic
 	}
 
-	callback := func(_ mqtt.Client, msg mqtt.Message) {
-		messageHandler(msg.Payload())
-	}
-	if token := c.mqttClient.Subscribe(c.mqttTopic, 0, callback); token.Wait() && token.Error() != nil {
-		return erax.Wrap(token.Error(), "failed to subscribe to mqtt topic")
+	return "$share/" + group + "/" + topic
+}
+
+func (c *Consumer) Run(messageHandler func(msg *uplink.Message) bool) error {
+	c.callback = func(_ mqtt.Client, msg mqtt.Message) {
+		if c.resuming.Load() {
+			return
+		}
+
+		if messageHandler(&uplink.Message{Topic: msg.Topic(), Payload: msg.Payload()}) {
+			msg.Ack()
+			return
+		}
+
+		if c.manualAck && c.resuming.CompareAndSwap(false, true) {
+			go c.resume()
+		}
 	}
 
-	return nil
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.connect()
 }
 
 func (c *Consumer) Stop() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stopped = true
+
 	var err error
 
 	if token := c.mqttClient.Unsubscribe(c.mqttTopic); token.Wait() && token.Error() != nil {
@@ -36,9 +80,47 @@ func (c *Consumer) Stop() error {
 	return err
 }
 
-func NewConsumer(mqttClient mqtt.Client, mqttTopic string) *Consumer {
+func (c *Consumer) connect() error {
+	if token := c.mqttClient.Connect(); token.Wait() && token.Error() != nil {
+		return erax.Wrap(token.Error(), "failed to connect to mqtt broker")
+	}
+
+	if token := c.mqttClient.Subscribe(c.mqttTopic, c.qos, c.callback); token.Wait() && token.Error() != nil {
+		return erax.Wrap(token.Error(), "failed to subscribe to mqtt topic")
+	}
+
+	return nil
+}
+
+// resume disconnects, so that the broker stops sending while msgChan drains, and reconnects after resumeDelay to
+// have the unacknowledged messages redelivered.
+func (c *Consumer) resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stopped {
+		return
+	}
+
+	zap.L().Warn("mqtt message refused, reconnecting for redelivery", zap.Duration("delay", c.resumeDelay))
+	c.mqttClient.Disconnect(250)
+	time.Sleep(c.resumeDelay)
+
+	c.resuming.Store(false)
+	if err := c.connect(); err != nil {
+		zap.L().Error("failed to reconnect to mqtt broker", zap.Error(err))
+	}
+}
+
+// NewConsumer subscribes to mqttTopic, as a shared subscription when shareGroup is set. With manualAck, mqttClient
+// must have auto-ack disabled and a persistent session.
+func NewConsumer(mqttClient mqtt.Client, mqttTopic, shareGroup string, qos byte, manualAck bool,
+	resumeDelay time.Duration) *Consumer {
 	return &Consumer{
-		mqttClient: mqttClient,
-		mqttTopic:  mqttTopic,
+		mqttClient:  mqttClient,
+		mqttTopic:   SharedTopic(shareGroup, mqttTopic),
+		qos:         qos,
+		manualAck:   manualAck,
+		resumeDelay: resumeDelay,
 	}
 }
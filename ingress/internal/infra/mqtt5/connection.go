@@ -0,0 +1,176 @@
+package mqtt5
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"go.uber.org/zap"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DangeL187/erax"
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
+)
+
+const connectTimeout = 30 * time.Second
+
+// Connection is an MQTT v5 connection shared by a Consumer and a Publisher. It reconnects automatically and, with a
+// non-zero session expiry, resumes the session so that QoS 1 messages sent while it was down are not lost.
+type Connection struct {
+	brokerURL     *url.URL
+	tlsConfig     *tls.Config
+	clientID      string
+	username      string
+	password      string
+	sessionExpiry time.Duration
+	manualAck     bool
+
+	onConnectionUp    func(cm *autopaho.ConnectionManager, connAck *paho.Connack)
+	onPublishReceived func(paho.PublishReceived) (bool, error)
+
+	// mu serialises reconnect and disconnect, so that a reconnection cannot outlive Stop
+	mu     sync.Mutex
+	closed bool
+
+	cm atomic.Pointer[autopaho.ConnectionManager]
+}
+
+// connect starts the connection and waits until it is up. onConnectionUp runs on every (re)connection, so that
+// subscriptions can be restored when the broker did not keep the session.
+func (c *Connection) connect(onConnectionUp func(cm *autopaho.ConnectionManager, connAck *paho.Connack),
+	onPublishReceived func(paho.PublishReceived) (bool, error)) error {
+	c.onConnectionUp = onConnectionUp
+	c.onPublishReceived = onPublishReceived
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.dial()
+}
+
+// reconnect replaces the connection, after delay, with a new one that resumes the session, so that the broker
+// redelivers the messages left unacknowledged on the old one. autopaho only reconnects after connection errors,
+// hence a new one.
+func (c *Connection) reconnect(delay time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+
+	if err := c.disconnectLocked(); err != nil {
+		zap.L().Warn("failed to disconnect cleanly before reconnecting", zap.Error(err))
+	}
+	time.Sleep(delay)
+
+	return c.dial()
+}
+
+func (c *Connection) dial() error {
+	cm, err := autopaho.NewConnection(context.Background(), autopaho.ClientConfig{
+		ServerUrls:                    []*url.URL{c.brokerURL},
+		TlsCfg:                        c.tlsConfig,
+		KeepAlive:                     30,
+		CleanStartOnInitialConnection: c.sessionExpiry == 0,
+		SessionExpiryInterval:         uint32(c.sessionExpiry.Seconds()),
+		ConnectTimeout:                connectTimeout,
+		ConnectUsername:               c.username,
+		ConnectPassword:               []byte(c.password),
+		OnConnectionUp:                c.onConnectionUp,
+		OnConnectError: func(err error) {
+			zap.L().Warn("failed to connect to mqtt broker", zap.Error(err))
+		},
+		ClientConfig: paho.ClientConfig{
+			ClientID:                   c.clientID,
+			EnableManualAcknowledgment: c.manualAck,
+			OnPublishReceived:          []func(paho.PublishReceived) (bool, error){c.onPublishReceived},
+			OnServerDisconnect: func(d *paho.Disconnect) {
+				zap.L().Warn("disconnected by mqtt broker", zap.Uint8("reason code", d.ReasonCode))
+			},
+			OnClientError: func(err error) {
+				zap.L().Error("mqtt client error", zap.Error(err))
+			},
+		},
+	})
+	if err != nil {
+		return erax.Wrap(err, "failed to create mqtt connection")
+	}
+	c.cm.Store(cm) // keeps connecting in the background if the wait below times out
+
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	defer cancel()
+
+	if err = cm.AwaitConnection(ctx); err != nil {
+		return erax.Wrap(err, "failed to connect to mqtt broker")
+	}
+
+	return nil
+}
+
+func (c *Connection) publish(ctx context.Context, pub *paho.Publish) error {
+	cm := c.cm.Load()
+	if cm == nil {
+		return errors.New("mqtt connection is not established")
+	}
+
+	if _, err := cm.Publish(ctx, pub); err != nil {
+		return erax.Wrap(err, "failed to publish")
+	}
+
+	return nil
+}
+
+func (c *Connection) disconnect() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+
+	return c.disconnectLocked()
+}
+
+func (c *Connection) disconnectLocked() error {
+	cm := c.cm.Load()
+	if cm == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+
+	if err := cm.Disconnect(ctx); err != nil {
+		return erax.Wrap(err, "failed to disconnect from mqtt broker")
+	}
+
+	return nil
+}
+
+// NewConnection creates an MQTT v5 connection to broker (host:port), over TLS unless tlsConfig is nil. An empty
+// username connects without credentials. A zero sessionExpiry starts a clean session that ends with the connection.
+// With manualAck, QoS 1 messages are acknowledged only when the Consumer's handler accepts them.
+func NewConnection(broker string, tlsConfig *tls.Config, clientID, username, password string,
+	sessionExpiry time.Duration, manualAck bool) (*Connection, error) {
+	scheme := "mqtt://"
+	if tlsConfig != nil {
+		scheme = "mqtts://"
+	}
+
+	brokerURL, err := url.Parse(scheme + broker)
+	if err != nil {
+		return nil, erax.Wrap(err, "failed to parse mqtt broker address")
+	}
+
+	return &Connection{
+		brokerURL:     brokerURL,
+		tlsConfig:     tlsConfig,
+		clientID:      clientID,
+		username:      username,
+		password:      password,
+		sessionExpiry: sessionExpiry,
+		manualAck:     manualAck,
+	}, nil
+}
@@ -0,0 +1,151 @@
+package mqtt5
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"sync/atomic"
+	"time"
+
+	"github.com/DangeL187/erax"
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
+
+	infraMqtt "ingress/internal/infra/mqtt"
+	"ingress/internal/shared/uplink"
+)
+
+// user properties a device may set instead of (or in addition to) payload fields
+const (
+	userPropertyToken         = "token"
+	userPropertyTraceParent   = "traceparent"
+	userPropertySchemaVersion = "schema_version"
+)
+
+type Consumer struct {
+	conn      *Connection
+	mqttTopic string
+	qos       byte
+
+	// resumeDelay is how long to stay disconnected after a refused message, so that msgChan can drain
+	resumeDelay time.Duration
+
+	// refusedBy is the client that refused a message and is being replaced. Acknowledgements are sent in order, so
+	// nothing after the refused message is handled on it; the broker redelivers it all when the session resumes.
+	refusedBy atomic.Pointer[paho.Client]
+
+	subscribed chan error
+}
+
+func (c *Consumer) Run(messageHandler func(msg *uplink.Message) bool) error {
+	onPublishReceived := func(pr paho.PublishReceived) (bool, error) {
+		if c.conn.manualAck && c.refusedBy.Load() == pr.Client {
+			return true, nil // left for redelivery with the refused message
+		}
+
+		msg := &uplink.Message{Topic: pr.Packet.Topic, Payload: pr.Packet.Payload}
+		if props := pr.Packet.Properties; props != nil {
+			msg.ContentType = props.ContentType
+			msg.TraceParent = props.User.Get(userPropertyTraceParent)
+			msg.Token = props.User.Get(userPropertyToken)
+			msg.SchemaVersion = props.User.Get(userPropertySchemaVersion)
+			msg.Reply = uplink.Reply{Topic: props.ResponseTopic, CorrelationData: props.CorrelationData}
+		}
+
+		accepted := messageHandler(msg)
+		if !c.conn.manualAck {
+			return true, nil
+		}
+
+		if accepted {
+			if err := pr.Client.Ack(pr.Packet); err != nil {
+				zap.L().Error("failed to acknowledge mqtt message", zap.Error(err))
+			}
+			return true, nil
+		}
+
+		// the broker only redelivers unacknowledged messages when the session resumes, so reconnect
+		c.refusedBy.Store(pr.Client)
+		zap.L().Warn("mqtt message refused, reconnecting for redelivery", zap.Duration("delay", c.resumeDelay))
+		go func() {
+			if err := c.conn.reconnect(c.resumeDelay); err != nil {
+				zap.L().Error("failed to reconnect to mqtt broker", zap.Error(err))
+			}
+		}()
+
+		return true, nil
+	}
+
+	if err := c.conn.connect(c.subscribe, onPublishReceived); err != nil {
+		return erax.Wrap(err, "failed to connect to mqtt broker")
+	}
+
+	select {
+	case err := <-c.subscribed:
+		if err != nil {
+			return erax.Wrap(err, "failed to subscribe to mqtt topic")
+		}
+	case <-time.After(connectTimeout):
+		return errors.New("timed out subscribing to mqtt topic")
+	}
+
+	return nil
+}
+
+func (c *Consumer) Stop() error {
+	var err error
+
+	if cm := c.conn.cm.Load(); cm != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if _, unsubErr := cm.Unsubscribe(ctx, &paho.Unsubscribe{Topics: []string{c.mqttTopic}}); unsubErr != nil {
+			err = erax.Wrap(unsubErr, "failed to unsubscribe from mqtt topic")
+		}
+	}
+
+	if disconnectErr := c.conn.disconnect(); disconnectErr != nil && err == nil {
+		err = disconnectErr
+	}
+
+	return err
+}
+
+// subscribe runs on every connection, so the subscription survives reconnects without a persisted session.
+func (c *Consumer) subscribe(cm *autopaho.ConnectionManager, _ *paho.Connack) {
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	defer cancel()
+
+	suback, err := cm.Subscribe(ctx, &paho.Subscribe{
+		Subscriptions: []paho.SubscribeOptions{{Topic: c.mqttTopic, QoS: c.qos}},
+	})
+	if err == nil {
+		for _, reason := range suback.Reasons {
+			if reason >= 0x80 {
+				err = fmt.Errorf("subscription refused with reason code 0x%02x", reason)
+				break
+			}
+		}
+	}
+	if err != nil {
+		zap.L().Error("failed to subscribe to mqtt topic", zap.String("topic", c.mqttTopic), zap.Error(err))
+	}
+
+	select {
+	case c.subscribed <- err:
+	default: // a reconnection, nobody is waiting
+	}
+}
+
+// NewConsumer subscribes to mqttTopic, as a shared subscription when shareGroup is set. With manual acknowledgement,
+// a refused message makes it reconnect after resumeDelay.
+func NewConsumer(conn *Connection, mqttTopic, shareGroup string, qos byte, resumeDelay time.Duration) *Consumer {
+	return &Consumer{
+		conn:        conn,
+		mqttTopic:   infraMqtt.SharedTopic(shareGroup, mqttTopic),
+		qos:         qos,
+		resumeDelay: resumeDelay,
+		subscribed:  make(chan error, 1),
+	}
+}
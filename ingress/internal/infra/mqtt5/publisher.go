@@ -0,0 +1,65 @@
+package mqtt5
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/DangeL187/erax"
+	"github.com/eclipse/paho.golang/paho"
+)
+
+const publishTimeout = 5 * time.Second
+
+type Publisher struct {
+	conn *Connection
+}
+
+func (p *Publisher) Publish(topic string, payload any) error {
+	return p.PublishResponse(topic, nil, payload)
+}
+
+// PublishResponse publishes payload with the correlation data of the request it answers, so that the device can
+// match the two.
+func (p *Publisher) PublishResponse(topic string, correlationData []byte, payload any) error {
+	data, err := payloadBytes(payload)
+	if err != nil {
+		return erax.Wrap(err, "failed to publish device data payload")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+
+	pub := &paho.Publish{
+		Topic:   topic,
+		QoS:     0,
+		Payload: data,
+		Properties: &paho.PublishProperties{
+			ContentType:     "application/json",
+			CorrelationData: correlationData,
+		},
+	}
+	if err = p.conn.publish(ctx, pub); err != nil {
+		return erax.Wrap(err, "failed to publish device data payload")
+	}
+
+	return nil
+}
+
+// payloadBytes accepts the payload types the v3 client does.
+func payloadBytes(payload any) ([]byte, error) {
+	switch p := payload.(type) {
+	case []byte:
+		return p, nil
+	case string:
+		return []byte(p), nil
+	default:
+		return nil, fmt.Errorf("unknown payload type %T", payload)
+	}
+}
+
+func NewPublisher(conn *Connection) *Publisher {
+	return &Publisher{
+		conn: conn,
+	}
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrorCode tells a device why its message was rejected and how to recover.
+type ErrorCode string
+
+const (
+	CodeTokenExpired     ErrorCode = "token_expired"     // refresh the access token
+	CodeSignatureInvalid ErrorCode = "signature_invalid" // the token cannot be verified, log in again
+	CodeIdentityMismatch ErrorCode = "identity_mismatch" // the token was sent under another device ID
+	CodeRateLimited      ErrorCode = "rate_limited"      // resend after RetryAfterMs
+	CodeDeviceDisabled   ErrorCode = "device_disabled"   // an operator disabled the device, stop sending
+	CodeUnavailable      ErrorCode = "unavailable"       // the token could not be checked, resend after RetryAfterMs
+)
+
+// UnavailableRetryAfter is how long devices are told to wait when their token could not be checked, e.g. while the
+// auth service is unreachable.
+const UnavailableRetryAfter = 5 * time.Second
+
+var errorMessages = map[ErrorCode]string{
+	CodeTokenExpired:     "token expired",
+	CodeSignatureInvalid: "invalid token",
+	CodeIdentityMismatch: ErrIdentityMismatch.Error(),
+	CodeRateLimited:      "rate limited",
+	CodeDeviceDisabled:   ErrDeviceDisabled.Error(),
+	CodeUnavailable:      "authentication unavailable",
+}
+
+// ErrorResponse is the payload of auth errors and throttle notices sent to devices.
+type ErrorResponse struct {
+	Code          ErrorCode `json:"code"`
+	Error         string    `json:"error"` // human-readable, for logs and devices that predate Code
+	RetryAfterMs  int64     `json:"retry_after_ms,omitempty"`
+	CorrelationID string    `json:"correlation_id,omitempty"` // of the rejected message, when it had one
+}
+
+func NewErrorResponse(code ErrorCode, correlationID string) ErrorResponse {
+	resp := ErrorResponse{
+		Code:          code,
+		Error:         errorMessages[code],
+		CorrelationID: correlationID,
+	}
+	if code == CodeUnavailable {
+		resp.RetryAfterMs = UnavailableRetryAfter.Milliseconds()
+	}
+
+	return resp
+}
+
+// CodeOf classifies an authentication error. Errors that say nothing about the token, e.g. the auth service being
+// unreachable, are unavailable rather than signature_invalid, so that devices retry instead of logging in again.
+func CodeOf(err error) ErrorCode {
+	switch {
+	case errors.Is(err, ErrTokenExpired):
+		return CodeTokenExpired
+	case errors.Is(err, ErrIdentityMismatch):
+		return CodeIdentityMismatch
+	case errors.Is(err, ErrDeviceDisabled):
+		return CodeDeviceDisabled
+	case errors.Is(err, ErrSignatureInvalid):
+		return CodeSignatureInvalid
+	default:
+		return CodeUnavailable
+	}
+}
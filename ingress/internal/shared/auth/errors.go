@@ -0,0 +1,8 @@
+package auth
+
+import "errors"
+
+var ErrIdentityMismatch = errors.New("token does not belong to device")
+var ErrTokenExpired = errors.New("token expired")
+var ErrDeviceDisabled = errors.New("device disabled")
+var ErrSignatureInvalid = errors.New("invalid token")
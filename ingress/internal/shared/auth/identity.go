@@ -0,0 +1,8 @@
+package auth
+
+import "time"
+
+type Identity struct {
+	DeviceID  string
+	ExpiresAt time.Time
+}
@@ -1,22 +1,112 @@
 package config
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"go.uber.org/zap"
 	"os"
+	"path"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+
+	"ingress/internal/shared/tlsutil"
+)
+
+const (
+	OverflowPolicyDrop  = "drop"
+	OverflowPolicyBlock = "block"
+	OverflowPolicyAck   = "ack"
+)
+
+const (
+	MQTTVersion3 = "3"
+	MQTTVersion5 = "5"
+)
+
+const (
+	ClockSkewPolicyFlag  = "flag"
+	ClockSkewPolicyClamp = "clamp"
+)
+
+const (
+	PartitionerHash           = "hash"
+	PartitionerConsistentHash = "consistent-hash"
 )
 
+// RouteRule sends messages matching all of its non-empty conditions to Topic instead of KAFKA_TOPIC.
+type RouteRule struct {
+	Name  string `json:"name"`
+	Topic string `json:"topic"`
+
+	DeviceID    string `json:"device_id"`   // glob, e.g. "pump-*" for a device group
+	MQTTTopic   string `json:"mqtt_topic"`  // glob, e.g. "devices/telemetry/alarm" for a message type
+	Measurement string `json:"measurement"` // measurement that must be present
+	Equals      string `json:"equals"`      // value Measurement must have, if set
+}
+
 type Config struct {
 	GRPCAddr     string
+	InstanceID   string
 	KafkaBroker  string
 	KafkaTopic   string
 	MQTTBroker   string
 	MQTTClientID string
 	MQTTTopic    string
 	MsgChanSize  int
+
+	MQTTPassword string
+	MQTTUsername string
+
+	MQTTSessionExpiry time.Duration
+	MQTTShareGroup    string
+	MQTTVersion       string
+
+	CoAPAddr         string
+	CoAPMaxBodyBytes int
+
+	GRPCIngestAddr string
+
+	HTTPAddr         string
+	HTTPMaxBodyBytes int64
+
+	AuthCacheSize int
+	JWTAudience   string
+	JWTIssuer     string
+
+	BatchMaxSize int
+
+	SeqTrackerSize int
+	SeqWindow      int
+
+	RateBurst        int
+	RateLimit        float64
+	RateLimitRefresh time.Duration
+	RateLimiterSize  int
+
+	DisabledDevicesRefresh time.Duration
+
+	OverflowPolicy  string
+	OverflowTimeout time.Duration
+
+	ClockSkewPolicy string
+	ClockSkewWindow time.Duration
+
+	KafkaDLQTopic        string
+	KafkaPartitioner     string
+	KafkaQuarantineTopic string
+	KafkaRetryBackoff    time.Duration
+	KafkaRetryMax        int
+	KafkaRoutes          []RouteRule
+
+	AuthGRPCTLS   tlsutil.Config
+	GRPCIngestTLS tlsutil.Config
+	HTTPTLS       tlsutil.Config
+	KafkaTLS      tlsutil.Config
+	MQTTTLS       tlsutil.Config
 }
 
 func NewConfig() (*Config, error) {
@@ -45,13 +135,280 @@ func NewConfig() (*Config, error) {
 		return nil, fmt.Errorf("invalid MSG_CHAN_SIZE: %s", vars["MSG_CHAN_SIZE"])
 	}
 
+	authCacheSize, err := getEnvInt("AUTH_CACHE_SIZE", 100000)
+	if err != nil || authCacheSize <= 0 {
+		return nil, fmt.Errorf("invalid AUTH_CACHE_SIZE: %s", os.Getenv("AUTH_CACHE_SIZE"))
+	}
+
+	seqWindow, err := getEnvInt("SEQ_WINDOW", 256)
+	if err != nil || seqWindow <= 0 || seqWindow%64 != 0 {
+		return nil, fmt.Errorf("invalid SEQ_WINDOW, must be a positive multiple of 64: %s", os.Getenv("SEQ_WINDOW"))
+	}
+
+	seqTrackerSize, err := getEnvInt("SEQ_TRACKER_SIZE", 100000)
+	if err != nil || seqTrackerSize <= 0 {
+		return nil, fmt.Errorf("invalid SEQ_TRACKER_SIZE: %s", os.Getenv("SEQ_TRACKER_SIZE"))
+	}
+
+	rateLimit, err := getEnvFloat("RATE_LIMIT", 200)
+	if err != nil || rateLimit < 0 {
+		return nil, fmt.Errorf("invalid RATE_LIMIT: %s", os.Getenv("RATE_LIMIT"))
+	}
+
+	rateBurst, err := getEnvInt("RATE_BURST", 400)
+	if err != nil || rateBurst <= 0 {
+		return nil, fmt.Errorf("invalid RATE_BURST: %s", os.Getenv("RATE_BURST"))
+	}
+
+	rateLimitRefresh, err := getEnvDuration("RATE_LIMIT_REFRESH", time.Minute)
+	if err != nil || rateLimitRefresh <= 0 {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_REFRESH: %s", os.Getenv("RATE_LIMIT_REFRESH"))
+	}
+
+	disabledDevicesRefresh, err := getEnvDuration("DISABLED_DEVICES_REFRESH", 30*time.Second)
+	if err != nil || disabledDevicesRefresh <= 0 {
+		return nil, fmt.Errorf("invalid DISABLED_DEVICES_REFRESH: %s", os.Getenv("DISABLED_DEVICES_REFRESH"))
+	}
+
+	rateLimiterSize, err := getEnvInt("RATE_LIMITER_SIZE", 100000)
+	if err != nil || rateLimiterSize <= 0 {
+		return nil, fmt.Errorf("invalid RATE_LIMITER_SIZE: %s", os.Getenv("RATE_LIMITER_SIZE"))
+	}
+
+	mqttShareGroup := os.Getenv("MQTT_SHARE_GROUP") // empty subscribes every instance to all messages
+	if strings.ContainsAny(mqttShareGroup, "/+#") {
+		return nil, fmt.Errorf("invalid MQTT_SHARE_GROUP: %s", mqttShareGroup)
+	}
+
+	mqttVersion := getEnv("MQTT_VERSION", MQTTVersion3)
+	switch mqttVersion {
+	case MQTTVersion3, MQTTVersion5:
+	default:
+		return nil, fmt.Errorf("invalid MQTT_VERSION: %s", mqttVersion)
+	}
+
+	mqttSessionExpiry, err := getEnvDuration("MQTT_SESSION_EXPIRY", 0)
+	if err != nil || mqttSessionExpiry < 0 {
+		return nil, fmt.Errorf("invalid MQTT_SESSION_EXPIRY: %s", os.Getenv("MQTT_SESSION_EXPIRY"))
+	}
+
+	coapMaxBodyBytes, err := getEnvInt("COAP_MAX_BODY_BYTES", 16<<10)
+	if err != nil || coapMaxBodyBytes <= 0 {
+		return nil, fmt.Errorf("invalid COAP_MAX_BODY_BYTES: %s", os.Getenv("COAP_MAX_BODY_BYTES"))
+	}
+
+	httpMaxBodyBytes, err := getEnvInt("HTTP_MAX_BODY_BYTES", 1<<20)
+	if err != nil || httpMaxBodyBytes <= 0 {
+		return nil, fmt.Errorf("invalid HTTP_MAX_BODY_BYTES: %s", os.Getenv("HTTP_MAX_BODY_BYTES"))
+	}
+
+	batchMaxSize, err := getEnvInt("BATCH_MAX_SIZE", 1000)
+	if err != nil || batchMaxSize <= 0 {
+		return nil, fmt.Errorf("invalid BATCH_MAX_SIZE: %s", os.Getenv("BATCH_MAX_SIZE"))
+	}
+
+	overflowPolicy := getEnv("OVERFLOW_POLICY", OverflowPolicyDrop)
+	switch overflowPolicy {
+	case OverflowPolicyDrop, OverflowPolicyBlock, OverflowPolicyAck:
+	default:
+		return nil, fmt.Errorf("invalid OVERFLOW_POLICY: %s", overflowPolicy)
+	}
+
+	// refused messages are redelivered only when the session resumes, which needs one that outlives the connection
+	if overflowPolicy == OverflowPolicyAck && mqttVersion == MQTTVersion5 && mqttSessionExpiry == 0 {
+		return nil, errors.New("OVERFLOW_POLICY=ack requires MQTT_SESSION_EXPIRY with MQTT_VERSION=5")
+	}
+
+	overflowTimeout, err := getEnvDuration("OVERFLOW_TIMEOUT", time.Second)
+	if err != nil || overflowTimeout <= 0 {
+		return nil, fmt.Errorf("invalid OVERFLOW_TIMEOUT: %s", os.Getenv("OVERFLOW_TIMEOUT"))
+	}
+
+	kafkaRetryMax, err := getEnvInt("KAFKA_RETRY_MAX", 3)
+	if err != nil || kafkaRetryMax < 1 { // the idempotent producer needs retries
+		return nil, fmt.Errorf("invalid KAFKA_RETRY_MAX: %s", os.Getenv("KAFKA_RETRY_MAX"))
+	}
+
+	kafkaRetryBackoff, err := getEnvDuration("KAFKA_RETRY_BACKOFF", 100*time.Millisecond)
+	if err != nil || kafkaRetryBackoff <= 0 {
+		return nil, fmt.Errorf("invalid KAFKA_RETRY_BACKOFF: %s", os.Getenv("KAFKA_RETRY_BACKOFF"))
+	}
+
+	kafkaPartitioner := getEnv("KAFKA_PARTITIONER", PartitionerHash)
+	switch kafkaPartitioner {
+	case PartitionerHash, PartitionerConsistentHash:
+	default:
+		return nil, fmt.Errorf("invalid KAFKA_PARTITIONER: %s", kafkaPartitioner)
+	}
+
+	clockSkewPolicy := getEnv("CLOCK_SKEW_POLICY", ClockSkewPolicyFlag)
+	switch clockSkewPolicy {
+	case ClockSkewPolicyFlag, ClockSkewPolicyClamp:
+	default:
+		return nil, fmt.Errorf("invalid CLOCK_SKEW_POLICY: %s", clockSkewPolicy)
+	}
+
+	clockSkewWindow, err := getEnvDuration("CLOCK_SKEW_WINDOW", 5*time.Minute)
+	if err != nil || clockSkewWindow <= 0 {
+		return nil, fmt.Errorf("invalid CLOCK_SKEW_WINDOW: %s", os.Getenv("CLOCK_SKEW_WINDOW"))
+	}
+
+	kafkaRoutes, err := parseRouteRules(os.Getenv("KAFKA_ROUTES"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid KAFKA_ROUTES: %w", err)
+	}
+
+	authGRPCTLS, err := tlsutil.FromEnv("AUTH_GRPC")
+	if err != nil {
+		return nil, err
+	}
+
+	grpcIngestTLS, err := tlsutil.FromEnv("GRPC_INGEST")
+	if err != nil {
+		return nil, err
+	}
+
+	httpTLS, err := tlsutil.FromEnv("HTTP")
+	if err != nil {
+		return nil, err
+	}
+
+	kafkaTLS, err := tlsutil.FromEnv("KAFKA")
+	if err != nil {
+		return nil, err
+	}
+
+	mqttTLS, err := tlsutil.FromEnv("MQTT")
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, _ := os.Hostname()
+
 	return &Config{
-		MsgChanSize:  msgChanSize,
-		GRPCAddr:     vars["AUTH_GRPC"],
-		KafkaBroker:  vars["KAFKA_BROKER"],
-		KafkaTopic:   vars["KAFKA_TOPIC"],
-		MQTTBroker:   vars["MQTT_BROKER"],
-		MQTTClientID: vars["MQTT_CLIENT_ID"],
-		MQTTTopic:    vars["MQTT_TOPIC"],
+		AuthCacheSize:  authCacheSize,
+		MsgChanSize:    msgChanSize,
+		GRPCAddr:       vars["AUTH_GRPC"],
+		InstanceID:     getEnv("INSTANCE_ID", hostname),
+		JWTAudience:    getEnv("JWT_AUDIENCE", "hivepulse"),
+		JWTIssuer:      getEnv("JWT_ISSUER", "http://localhost:8000"),
+		KafkaBroker:    vars["KAFKA_BROKER"],
+		KafkaTopic:     vars["KAFKA_TOPIC"],
+		MQTTBroker:     vars["MQTT_BROKER"],
+		MQTTClientID:   vars["MQTT_CLIENT_ID"],
+		MQTTTopic:      vars["MQTT_TOPIC"],
+		MQTTShareGroup: mqttShareGroup,
+
+		MQTTPassword: os.Getenv("MQTT_PASSWORD"),
+		MQTTUsername: os.Getenv("MQTT_USERNAME"), // empty connects without credentials
+
+		CoAPAddr:         os.Getenv("COAP_ADDR"), // empty disables CoAP ingest
+		CoAPMaxBodyBytes: coapMaxBodyBytes,
+
+		GRPCIngestAddr: os.Getenv("GRPC_INGEST_ADDR"), // empty disables gRPC ingest
+
+		HTTPAddr:         os.Getenv("HTTP_ADDR"), // empty disables HTTP ingest
+		HTTPMaxBodyBytes: int64(httpMaxBodyBytes),
+
+		BatchMaxSize: batchMaxSize,
+
+		MQTTSessionExpiry: mqttSessionExpiry, // MQTT v5 only, 0 ends the session with the connection
+		MQTTVersion:       mqttVersion,
+		OverflowPolicy:    overflowPolicy,
+		OverflowTimeout:   overflowTimeout,
+
+		ClockSkewPolicy: clockSkewPolicy,
+		ClockSkewWindow: clockSkewWindow,
+
+		SeqTrackerSize: seqTrackerSize,
+		SeqWindow:      seqWindow,
+
+		RateBurst:        rateBurst,
+		RateLimit:        rateLimit, // 0 disables the default limit
+		RateLimitRefresh: rateLimitRefresh,
+		RateLimiterSize:  rateLimiterSize,
+
+		DisabledDevicesRefresh: disabledDevicesRefresh,
+
+		KafkaDLQTopic:        os.Getenv("KAFKA_DLQ_TOPIC"), // empty disables dead-lettering
+		KafkaPartitioner:     kafkaPartitioner,
+		KafkaQuarantineTopic: os.Getenv("KAFKA_QUARANTINE_TOPIC"), // empty drops rejected messages
+		KafkaRetryBackoff:    kafkaRetryBackoff,
+		KafkaRetryMax:        kafkaRetryMax,
+		KafkaRoutes:          kafkaRoutes,
+
+		AuthGRPCTLS:   authGRPCTLS,
+		GRPCIngestTLS: grpcIngestTLS,
+		HTTPTLS:       httpTLS,
+		KafkaTLS:      kafkaTLS,
+		MQTTTLS:       mqttTLS,
 	}, nil
 }
+
+func parseRouteRules(value string) ([]RouteRule, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var rules []RouteRule
+	if err := json.Unmarshal([]byte(value), &rules); err != nil {
+		return nil, err
+	}
+
+	names := map[string]bool{"default": true}
+	for _, rule := range rules {
+		if rule.Name == "" || rule.Topic == "" {
+			return nil, fmt.Errorf("route must have a name and a topic")
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("duplicate route name: %s", rule.Name)
+		}
+		names[rule.Name] = true
+
+		for _, pattern := range []string{rule.DeviceID, rule.MQTTTopic} {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("route %s: invalid pattern %q", rule.Name, pattern)
+			}
+		}
+		if rule.Equals != "" && rule.Measurement == "" {
+			return nil, fmt.Errorf("route %s: equals requires a measurement", rule.Name)
+		}
+	}
+
+	return rules, nil
+}
+
+func getEnv(name, defaultValue string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+
+	return defaultValue
+}
+
+func getEnvDuration(name string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	return time.ParseDuration(value)
+}
+
+func getEnvFloat(name string, defaultValue float64) (float64, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	return strconv.ParseFloat(value, 64)
+}
+
+func getEnvInt(name string, defaultValue int) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	return strconv.Atoi(value)
+}
@@ -0,0 +1,53 @@
+package trace
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	traceParentVersion = "00"
+	sampledFlags       = "01"
+)
+
+// ChildTraceParent returns a W3C traceparent for a new span. The trace ID and flags are taken from parent when it
+// is a valid traceparent, so the span joins the device's trace; otherwise a new sampled trace is started.
+func ChildTraceParent(parent string) string {
+	traceID, flags := randomHex(16), sampledFlags
+	if parts := strings.Split(parent, "-"); len(parts) == 4 && isValid(parts) {
+		traceID, flags = parts[1], parts[3]
+	}
+
+	return traceParentVersion + "-" + traceID + "-" + randomHex(8) + "-" + flags
+}
+
+// isValid checks the version-00 format: 2-digit version, 32-digit trace ID, 16-digit parent ID and 2-digit
+// flags, all lowercase hex, with all-zero IDs forbidden.
+func isValid(parts []string) bool {
+	if parts[0] == "ff" || !isHex(parts[0], 2) || !isHex(parts[1], 32) || !isHex(parts[2], 16) || !isHex(parts[3], 2) {
+		return false
+	}
+
+	return strings.Trim(parts[1], "0") != "" && strings.Trim(parts[2], "0") != ""
+}
+
+func isHex(s string, length int) bool {
+	if len(s) != length {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+
+	return true
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,29 @@
+package uplink
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrBatchTooLarge is returned for batch envelopes with more readings than BATCH_MAX_SIZE.
+var ErrBatchTooLarge = errors.New("batch has too many readings")
+
+// Batch is a batch envelope decoded by a codec: readings of one device sent with a single token. Readings leave ID
+// and Token empty, or repeat the envelope's.
+type Batch struct {
+	ID       string
+	Token    string
+	Readings []Telemetry
+}
+
+// IsBatchTopic reports whether an uplink carries a batch envelope, which devices send to a topic or path with a
+// "batch" level, e.g. devices/telemetry/batch or devices/telemetry/batch/cbor.
+func IsBatchTopic(topic string) bool {
+	for level := range strings.SplitSeq(topic, "/") {
+		if level == "batch" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,120 @@
+package uplink
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+type MeasurementKind uint8
+
+const (
+	MeasurementNumber MeasurementKind = iota + 1
+	MeasurementBool
+	MeasurementString
+)
+
+// Measurement is a single named sensor reading. Exactly one of the value fields is set, according to Kind.
+type Measurement struct {
+	Kind   MeasurementKind
+	Number float64
+	Bool   bool
+	String string
+}
+
+func NumberMeasurement(v float64) Measurement {
+	return Measurement{Kind: MeasurementNumber, Number: v}
+}
+
+func BoolMeasurement(v bool) Measurement {
+	return Measurement{Kind: MeasurementBool, Bool: v}
+}
+
+func StringMeasurement(v string) Measurement {
+	return Measurement{Kind: MeasurementString, String: v}
+}
+
+// MeasurementFromAny converts a value decoded by a self-describing codec (JSON, CBOR, MessagePack).
+func MeasurementFromAny(v any) (Measurement, error) {
+	switch v := v.(type) {
+	case float64:
+		return NumberMeasurement(v), nil
+	case float32:
+		return NumberMeasurement(float64(v)), nil
+	case int:
+		return NumberMeasurement(float64(v)), nil
+	case int8:
+		return NumberMeasurement(float64(v)), nil
+	case int16:
+		return NumberMeasurement(float64(v)), nil
+	case int32:
+		return NumberMeasurement(float64(v)), nil
+	case int64:
+		return NumberMeasurement(float64(v)), nil
+	case uint8:
+		return NumberMeasurement(float64(v)), nil
+	case uint16:
+		return NumberMeasurement(float64(v)), nil
+	case uint32:
+		return NumberMeasurement(float64(v)), nil
+	case uint64:
+		return NumberMeasurement(float64(v)), nil
+	case bool:
+		return BoolMeasurement(v), nil
+	case string:
+		return StringMeasurement(v), nil
+	default:
+		return Measurement{}, fmt.Errorf("unsupported measurement type %T", v)
+	}
+}
+
+// MeasurementsFromMap converts all values of a decoded measurements map, returning nil for an empty map.
+func MeasurementsFromMap(values map[string]any) (map[string]Measurement, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	measurements := make(map[string]Measurement, len(values))
+	for name, value := range values {
+		measurement, err := MeasurementFromAny(value)
+		if err != nil {
+			return nil, fmt.Errorf("measurement %q: %w", name, err)
+		}
+		measurements[name] = measurement
+	}
+
+	return measurements, nil
+}
+
+func (m Measurement) IsFinite() bool {
+	return m.Kind != MeasurementNumber || !(math.IsNaN(m.Number) || math.IsInf(m.Number, 0))
+}
+
+// MarshalJSON encodes the measurement as a bare JSON number, boolean or string.
+func (m Measurement) MarshalJSON() ([]byte, error) {
+	switch m.Kind {
+	case MeasurementNumber:
+		return json.Marshal(m.Number)
+	case MeasurementBool:
+		return json.Marshal(m.Bool)
+	case MeasurementString:
+		return json.Marshal(m.String)
+	default:
+		return nil, fmt.Errorf("unknown measurement kind %d", m.Kind)
+	}
+}
+
+func (m *Measurement) UnmarshalJSON(data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	measurement, err := MeasurementFromAny(v)
+	if err != nil {
+		return err
+	}
+	*m = measurement
+
+	return nil
+}
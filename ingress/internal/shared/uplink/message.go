@@ -0,0 +1,55 @@
+package uplink
+
+import (
+	"encoding/hex"
+	"time"
+	"unicode/utf8"
+
+	"ingress/internal/shared/auth"
+)
+
+// Message is a raw uplink as received by a consumer, before decoding.
+type Message struct {
+	ReceivedAt    time.Time // set by the ConsumerLoop when the consumer leaves it empty
+	Topic         string
+	ContentType   string // empty unless the transport carries one (e.g. MQTT v5)
+	TraceParent   string // W3C traceparent sent by the device, if any
+	Token         string // device token sent outside the payload (e.g. an MQTT v5 user property), if any
+	DeviceID      string // device the consumer already verified Token for (e.g. at gRPC stream start), if any
+	SchemaVersion string // payload layout version sent by the device (e.g. an MQTT v5 user property), if any
+	Admitted      int    // leading readings the consumer already charged to DeviceID's rate limit, if any
+	Reply         Reply
+	Payload       []byte
+
+	// Decoded is set by consumers that decoded Payload already (e.g. to check it against the token), so that it is
+	// not decoded twice.
+	Decoded *Decoded
+}
+
+// Decoded is a payload decoded by a codec: Telemetry for a single reading, Batch for a batch envelope.
+type Decoded struct {
+	Telemetry Telemetry
+	Batch     Batch
+	Codec     string
+	Encoding  string // compression of a batch envelope
+}
+
+// Reply is where the device asked for responses to this message to be sent (MQTT v5 response topic and
+// correlation data). An empty Topic means the device's default response topics.
+type Reply struct {
+	Topic           string
+	CorrelationData []byte
+
+	// Respond, when set, delivers auth errors over the transport the message came in on (e.g. a gRPC stream)
+	// instead of MQTT. It must not block.
+	Respond func(resp auth.ErrorResponse) error
+}
+
+// CorrelationID identifies the message in error responses: its correlation data, hex-encoded unless it is text.
+func (r Reply) CorrelationID() string {
+	if utf8.Valid(r.CorrelationData) {
+		return string(r.CorrelationData)
+	}
+
+	return hex.EncodeToString(r.CorrelationData)
+}
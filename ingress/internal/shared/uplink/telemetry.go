@@ -0,0 +1,15 @@
+package uplink
+
+// Telemetry is the internal record every codec decodes an uplink into.
+type Telemetry struct {
+	ID        string
+	Token     string
+	Latitude  float64
+	Longitude float64
+	Altitude  float64
+	Battery   float64
+	Timestamp int64  // as sent by the device, in any unit accepted by UnixMilli
+	Seq       uint64 // per-device sequence number, 0 when the device does not send one
+
+	Measurements map[string]Measurement
+}
@@ -0,0 +1,24 @@
+package uplink
+
+// Bounds separating timestamp units: a seconds value reaches 1e11 only in the year 5138, while millisecond
+// values passed 1e11 in 1973, so any plausible device clock falls unambiguously into one range.
+const (
+	maxUnixSeconds = 1e11
+	maxUnixMillis  = 1e14
+	maxUnixMicros  = 1e17
+)
+
+// UnixMilli converts a device timestamp in unix seconds, milliseconds, microseconds or nanoseconds, detected
+// by magnitude, to unix milliseconds.
+func UnixMilli(timestamp int64) int64 {
+	switch {
+	case timestamp < maxUnixSeconds:
+		return timestamp * 1000
+	case timestamp < maxUnixMillis:
+		return timestamp
+	case timestamp < maxUnixMicros:
+		return timestamp / 1000
+	default:
+		return timestamp / 1_000_000
+	}
+}
@@ -0,0 +1,37 @@
+-- Migrates an existing device_data table from second to millisecond timestamps (DateTime64(3)).
+--
+-- timestamp is part of the partition and sorting keys, so its type cannot be altered in place: the data is copied
+-- into a new table that is then swapped in atomically. Stop the consumer service before running this script; the
+-- messages produced in the meantime stay in Kafka and are written once it is started again.
+--
+-- Tables created from the current init.sql already use DateTime64(3) and need no migration.
+
+CREATE TABLE IF NOT EXISTS device_data_ms
+(
+    id String,
+    latitude Float64,
+    longitude Float64,
+    altitude Float64,
+    battery Float64,
+    timestamp DateTime64(3),
+    measurements_number Map(LowCardinality(String), Float64),
+    measurements_bool Map(LowCardinality(String), Bool),
+    measurements_string Map(LowCardinality(String), String),
+    ingested_at DateTime64(3),
+    received_at DateTime64(3),
+    clock_skewed Bool,
+    seq UInt64
+)
+ENGINE = MergeTree()
+PARTITION BY toYYYYMM(timestamp)
+ORDER BY (timestamp, id)
+TTL timestamp + INTERVAL 24 HOUR;
+
+INSERT INTO device_data_ms
+SELECT id, latitude, longitude, altitude, battery, toDateTime64(timestamp, 3),
+       measurements_number, measurements_bool, measurements_string, ingested_at, received_at, clock_skewed, seq
+FROM device_data;
+
+EXCHANGE TABLES device_data AND device_data_ms;
+
+DROP TABLE device_data_ms;
@@ -11,5 +11,15 @@ CREATE TABLE IF NOT EXISTS devices
     id            SERIAL PRIMARY KEY,
     device_id     TEXT NOT NULL UNIQUE,
     password_hash TEXT NOT NULL,
-    created_at    TIMESTAMP DEFAULT now()
+    created_at    TIMESTAMP DEFAULT now(),
+    rate_limit    DOUBLE PRECISION, -- messages per second, NULL for the ingress default, 0 for unlimited
+    rate_burst    INTEGER,          -- NULL for the ingress default
+    disabled      BOOLEAN NOT NULL DEFAULT FALSE
 );
+
+-- per-device ingress rate limit overrides, for databases created before they were introduced
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS rate_limit DOUBLE PRECISION;
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS rate_burst INTEGER;
+
+-- disabled devices, for databases created before they were introduced
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS disabled BOOLEAN NOT NULL DEFAULT FALSE;